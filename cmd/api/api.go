@@ -6,6 +6,7 @@ import (
 	"FIDOtestBackendApp/internal/graphql"
 	"FIDOtestBackendApp/internal/store"
 	"FIDOtestBackendApp/internal/store/cache"
+	"FIDOtestBackendApp/internal/validation"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -13,6 +14,7 @@ import (
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -22,6 +24,10 @@ type application struct {
 	store          store.Storage
 	cacheStorage   cache.Storage
 	graphqlStorage *graphql.GPQLStorage
+	breedProvider  validation.BreedProvider
+	// breedAPI is the TheCatAPI client when it is one of the providers, nil
+	// otherwise.
+	breedAPI *validation.Client
 }
 
 type dbConfig struct {
@@ -35,12 +41,41 @@ type redisConfig struct {
 	password string
 	db       int
 	enabled  bool
+	// prefix namespaces every key this service writes.
+	prefix string
+	breeds cacheTTLConfig
+	// memoryCapacity bounds the in-process cache used when Redis is
+	// disabled.
+	memoryCapacity int
+	// recordTTL is how long cats and missions stay cached, zero turns the
+	// record cache off.
+	recordTTL time.Duration
+}
+type cacheTTLConfig struct {
+	valid   time.Duration
+	invalid time.Duration
+}
+type jobsConfig struct {
+	salaryInterval  time.Duration
+	overdueInterval time.Duration
+}
+type breedsConfig struct {
+	provider     string
+	file         string
+	static       string
+	apiURL       string
+	syncInterval time.Duration
+	cacheTTL     time.Duration
+	// verifyInterval is how often cats with a pending breed are checked.
+	verifyInterval time.Duration
 }
 type config struct {
 	addr        string
 	db          dbConfig
 	env         string
 	redisConfig redisConfig
+	jobs        jobsConfig
+	breeds      breedsConfig
 }
 
 type CustomValidator struct {
@@ -82,6 +117,12 @@ func (app *application) mount() http.Handler {
 		}
 	})
 	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		// The timeout handler buffers the whole response, which would defeat
+		// streaming exports. The store's Export methods skip QueryTimeOut as
+		// well, so only the request context limits how long an export runs.
+		Skipper: func(c echo.Context) bool {
+			return strings.HasPrefix(c.Path(), "/v1/export/")
+		},
 		Timeout: 60 * time.Second,
 	}))
 	v1 := e.Group("/v1")
@@ -95,26 +136,67 @@ func (app *application) mount() http.Handler {
 
 	mission := v1.Group("/mission")
 	app.registerMissionGroup(mission)
+
+	targets := v1.Group("/targets")
+	app.registerTargetGroup(targets)
+
+	breeds := v1.Group("/breeds")
+	app.registerBreedGroup(breeds)
+
+	export := v1.Group("/export", middleware.Gzip())
+	export.GET("/:resource", app.exportHandler)
+
+	admin := v1.Group("/admin")
+	app.registerAdminGroup(admin)
 	return e
 }
 
 func (app *application) registerCatGroup(g *echo.Group) {
 	g.POST("", app.createCatHandler)
+	g.GET("/available", app.getAvailableCatsHandler)
+	g.POST("/import", app.importCatsHandler)
 	g.DELETE("/:id", app.deleteCatHandler)
 	g.GET("/:id", app.getCatByIDHandler)
 	g.PATCH("/:id", app.updateCatHandler)
+	g.POST("/:id/restore", app.restoreCatHandler)
+	g.GET("/:id/missions", app.getCatMissionsHandler)
+	g.GET("/:id/salary-history", app.getSalaryHistoryHandler)
+	g.POST("/:id/salary-history", app.scheduleSalaryChangeHandler)
 	g.GET("", app.getPaginatedCatListHandler)
 }
 
 func (app *application) registerMissionGroup(g *echo.Group) {
 	g.POST("", app.createMissionHandler)
+	g.GET("", app.getMissions)
 	g.GET("/mission_list", app.getMissions)
 	g.GET("/:id", app.getOneMission)
 	g.DELETE("/:id", app.deleteMissionHandler)
 	g.PATCH("/:id", app.updateMissionStatus)
+	g.GET("/:mission_id/targets", app.getMissionTargets)
+	g.GET("/:mission_id/target/:target_id", app.getTarget)
 	g.PATCH("/:mission_id/target/:target_id", app.updateTargetNote)
 	g.PATCH("/:mission_id/target_status/:target_id", app.updateTargetStatus)
 	g.DELETE("/:mission_id/target/:target_id", app.deleteTarget)
 	g.POST("/:mission_id/target", app.addTarget)
 	g.PATCH("/:id/cat/:cat_id", app.addCatToMission)
+	g.DELETE("/:id/cat", app.unassignCatFromMission)
+	g.POST("/:id/reassign", app.reassignCat)
+	g.POST("/:id/transition", app.transitionMission)
+	g.PATCH("/:id/schedule", app.updateMissionSchedule)
+	g.PATCH("/:mission_id/target/:target_id/schedule", app.updateTargetSchedule)
+}
+
+func (app *application) registerTargetGroup(g *echo.Group) {
+	g.GET("", app.getTargetListHandler)
+}
+
+func (app *application) registerBreedGroup(g *echo.Group) {
+	g.GET("", app.getBreedListHandler)
+	g.GET("/:name", app.getBreedHandler)
+}
+
+func (app *application) registerAdminGroup(g *echo.Group) {
+	g.DELETE("/spycat/:id", app.purgeCatHandler)
+	g.POST("/breeds/sync", app.syncBreedsHandler)
+	g.GET("/cache/stats", app.cacheStatsHandler)
 }
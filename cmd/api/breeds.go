@@ -0,0 +1,282 @@
+package main
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"FIDOtestBackendApp/internal/store/cache"
+	"FIDOtestBackendApp/internal/validation"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// breedSuggestions is how many close matches an unknown breed error lists.
+const breedSuggestions = 5
+
+// UnknownBreedError is the 422 body for a breed that is not in the catalog.
+type UnknownBreedError struct {
+	Message     string   `json:"error"`
+	Breed       string   `json:"breed"`
+	Suggestions []string `json:"suggestions"`
+}
+
+func (e *UnknownBreedError) Error() string {
+	return fmt.Sprintf("unknown breed %q", e.Breed)
+}
+
+type BreedSyncResult struct {
+	Synced int `json:"synced"`
+}
+
+// Get breed list godoc
+//
+//	@Summary		Fetches the breed directory
+//	@Description	Fetches catalog breeds sorted by name, each with the number of active spy cats of that breed.
+//	@Description	Offset mode returns a plain array, passing cursor returns a page envelope with next_cursor, prev_cursor and total.
+//	@Tags			breeds
+//	@Produce		json
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			search		query		string	false	"Case-insensitive name search"
+//	@Param			cursor		query		string	false	"Opaque cursor, switches to keyset pagination (empty for the first page)"
+//	@Param			with_total	query		bool	false	"Include the total number of matching breeds"
+//	@Success		200			{object}	[]store.BreedWithCats
+//	@Failure		400			{object}	error
+//	@Failure		422			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/breeds [get]
+func (app *application) getBreedListHandler(c echo.Context) error {
+	filterDefault := store.BreedListQuery{
+		PaginatedQuery: store.PaginatedQuery{
+			Limit:  10,
+			Offset: 0,
+		},
+	}
+	filterQuery, err := filterDefault.Parse(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if err = Validate.Struct(filterQuery); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	breeds, err := app.store.Breed.GetBreedList(c.Request().Context(), filterQuery)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			return c.JSON(http.StatusBadRequest, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return writePage(c, filterQuery.PaginatedQuery, breeds)
+}
+
+// Get breed godoc
+//
+//	@Summary		Get breed details
+//	@Description	Get origin, temperament, description and active spy cat count of a breed. The name is matched ignoring case and whitespace.
+//	@Tags			breeds
+//	@Produce		json
+//	@Param			name	path		string	true	"Breed name"
+//	@Success		200		{object}	store.BreedWithCats
+//	@Failure		422		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/breeds/{name} [get]
+func (app *application) getBreedHandler(c echo.Context) error {
+	breed, err := app.store.Breed.GetBreed(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, breed)
+}
+
+// Sync breeds godoc
+//
+//	@Summary		Sync breed catalog
+//	@Description	Refresh the local breed catalog from the upstream breed API right away instead of waiting for the scheduled sync.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	BreedSyncResult
+//	@Failure		500	{object}	error
+//	@Failure		503	{object}	error
+//	@Router			/admin/breeds/sync [post]
+func (app *application) syncBreedsHandler(c echo.Context) error {
+	synced, err := app.refreshBreeds(c.Request().Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, validation.ErrCircuitOpen), errors.Is(err, validation.ErrUnavailable):
+			return c.JSON(http.StatusServiceUnavailable, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, BreedSyncResult{Synced: synced})
+}
+
+// syncBreeds is the background job that keeps the breed catalog fresh.
+func (app *application) syncBreeds(ctx context.Context) error {
+	synced, err := app.refreshBreeds(ctx)
+	if err != nil {
+		return err
+	}
+	app.logger.Infow("breed catalog synced", "breeds", synced)
+	return nil
+}
+
+func (app *application) refreshBreeds(ctx context.Context) (int, error) {
+	upstream, err := validation.Refresh(ctx, app.breedProvider)
+	if err != nil {
+		return 0, err
+	}
+
+	breeds := make([]*store.Breed, 0, len(upstream))
+	for _, b := range upstream {
+		breeds = append(breeds, &store.Breed{
+			ExternalID:  b.ID,
+			Name:        b.Name,
+			Origin:      b.Origin,
+			Temperament: b.Temperament,
+			Description: b.Description,
+		})
+	}
+	synced, err := app.store.Breed.UpsertBreeds(ctx, breeds)
+	if err != nil {
+		return 0, err
+	}
+
+	names, err := app.store.Breed.BreedNames(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err = app.cacheStorage.Breeds.Warm(ctx, names); err != nil {
+		// The catalog is updated, a cold cache only costs extra lookups.
+		app.logger.Warnw("breed cache warm-up failed", "error", err)
+	}
+	return synced, nil
+}
+
+// canonicalBreed resolves breed to its catalog name, ignoring case and extra
+// whitespace. An unknown breed is an *UnknownBreedError with the closest
+// catalog names. Both outcomes are cached.
+func (app *application) canonicalBreed(ctx context.Context, breed string) (string, error) {
+	if err := Validate.VarCtx(ctx, breed, "required,max=200"); err != nil {
+		return "", ValidationError
+	}
+
+	entry, err := app.cacheStorage.Breeds.Get(ctx, breed)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		if entry, err = app.checkBreed(ctx, breed); err != nil {
+			return "", err
+		}
+		if err = app.cacheStorage.Breeds.Set(ctx, breed, *entry); err != nil {
+			return "", err
+		}
+	}
+
+	if !entry.Valid {
+		unknown := &UnknownBreedError{Breed: breed, Suggestions: entry.Suggestions}
+		if unknown.Suggestions == nil {
+			unknown.Suggestions = []string{}
+		}
+		unknown.Message = unknown.Error()
+		return "", unknown
+	}
+	return entry.Canonical, nil
+}
+
+// checkBreed looks breed up in the catalog.
+func (app *application) checkBreed(ctx context.Context, breed string) (*cache.BreedEntry, error) {
+	match, err := app.store.Breed.MatchBreed(ctx, breed)
+	switch {
+	case err == nil:
+		return &cache.BreedEntry{Valid: true, Canonical: match.Name}, nil
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+
+	names, err := app.store.Breed.BreedNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Before the first sync every breed would be unknown, which must not be
+	// cached or reported as a wrong breed.
+	if len(names) == 0 {
+		return nil, EmptyBreedCatalog
+	}
+	return &cache.BreedEntry{Suggestions: validation.Suggest(breed, names, breedSuggestions)}, nil
+}
+
+// pendingBreedBatch is how many pending cats one verification run handles.
+const pendingBreedBatch = 100
+
+// verifyPendingBreeds is the background job that settles cats created with a
+// deferred breed check. It waits while the catalog is empty, since an empty
+// catalog would reject every breed.
+func (app *application) verifyPendingBreeds(ctx context.Context) error {
+	cats, err := app.store.Cat.GetPendingBreedCats(ctx, pendingBreedBatch)
+	if err != nil || len(cats) == 0 {
+		return err
+	}
+	names, err := app.store.Breed.BreedNames(ctx)
+	if err != nil || len(names) == 0 {
+		return err
+	}
+
+	verified, rejected := 0, 0
+	for _, cat := range cats {
+		breed, status := cat.Breed, store.BreedStatusRejected
+		match, err := app.store.Breed.MatchBreed(ctx, cat.Breed)
+		switch {
+		case err == nil:
+			breed, status = match.Name, store.BreedStatusVerified
+			verified++
+		case errors.Is(err, store.ErrNotFound):
+			rejected++
+		default:
+			return err
+		}
+		if err = app.store.Cat.ResolveBreed(ctx, cat.ID, breed, status); err != nil {
+			return err
+		}
+	}
+	app.logger.Infow("pending breeds checked", "verified", verified, "rejected", rejected)
+	return nil
+}
+
+// newBreedProvider builds the provider named in the config. A comma separated
+// list, e.g. "file,thecatapi", is tried in that order.
+func newBreedProvider(cfg breedsConfig) (validation.BreedProvider, *validation.Client, error) {
+	var chain validation.ChainProvider
+	var api *validation.Client
+	for _, name := range strings.Split(cfg.provider, ",") {
+		switch strings.TrimSpace(name) {
+		case "thecatapi":
+			api = validation.NewClient(cfg.apiURL, validation.WithCacheTTL(cfg.cacheTTL))
+			chain = append(chain, api)
+		case "file":
+			if cfg.file == "" {
+				return nil, nil, fmt.Errorf("breed provider file needs BREEDS_FILE")
+			}
+			chain = append(chain, &validation.FileProvider{Path: cfg.file})
+		case "static":
+			chain = append(chain, validation.NewStaticProvider(strings.Split(cfg.static, ",")...))
+		default:
+			return nil, nil, fmt.Errorf("unknown breed provider %q", name)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0], api, nil
+	}
+	return chain, api, nil
+}
@@ -2,16 +2,27 @@ package main
 
 import (
 	"FIDOtestBackendApp/internal/store"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 var (
-	ValidationError = errors.New("validation error")
+	ValidationError      = errors.New("validation error")
+	UnsupportedMediaType = errors.New("unsupported media type")
+	EmptyBreedCatalog    = errors.New("breed catalog is empty")
 )
 
+const mimeMergePatchJSON = "application/merge-patch+json"
+
 type CreateCatPayload struct {
 	Name       string `json:"name" validate:"required,max=200"`
 	Experience int    `json:"year_of_experience" validate:"required,gte=1"`
@@ -19,66 +30,107 @@ type CreateCatPayload struct {
 	Salary     int    `json:"salary" validate:"required,gte=1"`
 }
 
+// UpdateCatInfoPayload is an RFC 7396 merge patch of a spy cat. Only the
+// members present in the request body are changed.
 type UpdateCatInfoPayload struct {
-	Salary int `json:"salary" validate:"required,gte=0"`
+	Name       *string `json:"name" validate:"omitnil,min=1,max=200"`
+	Experience *int    `json:"year_of_experience" validate:"omitnil,gte=1"`
+	Breed      *string `json:"breed" validate:"omitnil,max=200,breed-exits"`
+	Salary     *int    `json:"salary" validate:"omitnil,gte=0"`
+	// SalaryReason is not part of the cat, it is recorded in the salary
+	// history when the salary changes.
+	SalaryReason *string `json:"salary_reason" validate:"omitnil,max=255"`
 }
 
 // Create SpyCat
 //
 //	@Summary		Create spy cat
-//	@Description	Create new spy cat
+//	@Description	Create new spy cat. The breed is matched against the catalog ignoring case and whitespace and stored under its catalog name.
+//	@Description	An unknown breed is answered with 422 and the closest catalog breeds as suggestions.
+//	@Description	With breed_check=deferred a breed that cannot be checked right away, because the catalog is empty or unreachable, is stored as pending (202) and verified later by a background job.
+//	@Description	A breed the catalog already knows to be wrong is still answered with 422.
 //	@Tags			spycat
 //	@Accept			json
 //	@Produce		json
-//	@Param			payload	body		CreateCatPayload	true	"SpyCat payload"
-//	@Success		201		{object}	store.Cat
-//	@Failure		400		{object}	error
-//	@Failure		422		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			payload		body		CreateCatPayload	true	"SpyCat payload"
+//	@Param			breed_check	query		string				false	"Breed check mode"	Enums(sync, deferred)	default(sync)
+//	@Success		201			{object}	store.Cat
+//	@Success		202			{object}	store.Cat
+//	@Failure		400			{object}	error
+//	@Failure		422			{object}	error
+//	@Failure		500			{object}	error
+//	@Failure		503			{object}	error
 //	@Router			/spycat [post]
 func (app *application) createCatHandler(c echo.Context) error {
 	var payload CreateCatPayload
 	if err := c.Bind(&payload); err != nil {
 		return c.JSON(http.StatusBadRequest, ValidationError.Error())
 	}
-	breed, err := app.cacheStorage.Cats.Get(c.Request().Context(), payload.Breed)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+	deferred := false
+	switch c.QueryParam("breed_check") {
+	case "", "sync":
+	case "deferred":
+		deferred = true
+	default:
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
 	}
-	if !breed {
-		if err = Validate.Struct(payload); err != nil {
-			return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
-		}
-		err = app.cacheStorage.Cats.Set(c.Request().Context(), payload.Breed)
-		if err != nil {
+
+	breedStatus := store.BreedStatusVerified
+	breed, err := app.validateCatPayload(c.Request().Context(), payload, payload.Breed)
+	var unknown *UnknownBreedError
+	if err != nil && deferred && !errors.Is(err, ValidationError) && !errors.As(err, &unknown) {
+		// Only a check that could not run is deferred, a breed the catalog
+		// rejected is answered right away.
+		app.logger.Infow("breed check deferred", "breed", payload.Breed, "reason", err)
+		breed, breedStatus, err = strings.TrimSpace(payload.Breed), store.BreedStatusPending, nil
+	}
+	if err != nil {
+		switch {
+		case errors.As(err, &unknown):
+			return c.JSON(http.StatusUnprocessableEntity, unknown)
+		case errors.Is(err, ValidationError):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, EmptyBreedCatalog):
+			return c.JSON(http.StatusServiceUnavailable, err.Error())
+		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
 	}
+	payload.Breed = breed
 
 	spyCat := &store.Cat{
-		Name:       payload.Name,
-		Breed:      payload.Breed,
-		Experience: payload.Experience,
-		Salary:     payload.Salary,
+		Name:        payload.Name,
+		Breed:       payload.Breed,
+		BreedStatus: breedStatus,
+		Experience:  payload.Experience,
+		Salary:      payload.Salary,
 	}
 
 	if err := app.store.Cat.CreateSpyCat(c.Request().Context(), spyCat); err != nil {
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
+	if breedStatus == store.BreedStatusPending {
+		return c.JSON(http.StatusAccepted, spyCat)
+	}
 	return c.JSON(http.StatusOK, spyCat)
 }
 
 // Delete cat godoc
 //
 //	@Summary		Delete spy cat
-//	@Description	Delete spy cat by ID
+//	@Description	Archive spy cat by ID. The cat is hidden from lookups and lists but can be restored.
+//	@Description	Cats on an incomplete mission are refused with 409 unless force is set, which unassigns them and records the reason.
 //	@Tags			spycat
 //	@Produce		json
-//	@Param			id	path		int	true	"Cat ID"
-//	@Success		204	{object}	nil
-//	@Failure		422	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			id		path		int		true	"Cat ID"
+//	@Param			force	query		bool	false	"Unassign the cat from its active missions"
+//	@Param			reason	query		string	false	"Why the cat was unassigned, recorded with force"
+//	@Success		204		{object}	nil
+//	@Failure		400		{object}	error
+//	@Failure		409		{object}	store.ActiveMissionError
+//	@Failure		422		{object}	error
+//	@Failure		500		{object}	error
 //	@Router			/spycat/{id} [delete]
 func (app *application) deleteCatHandler(c echo.Context) error {
 	cat, err := app.getCatByID(c)
@@ -92,11 +144,29 @@ func (app *application) deleteCatHandler(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
 	}
-	err = app.store.Cat.DeleteSpyCat(c.Request().Context(), cat.ID)
+	opts := store.DeleteCatOptions{
+		Reason: c.QueryParam("reason"),
+	}
+	if err = Validate.Var(opts.Reason, "max=255"); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+	if force := c.QueryParam("force"); force != "" {
+		if opts.Force, err = strconv.ParseBool(force); err != nil {
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
+	}
+	if opts.Reason == "" {
+		opts.Reason = "cat deleted"
+	}
+
+	err = app.store.Cat.DeleteSpyCat(c.Request().Context(), cat.ID, opts)
 	if err != nil {
+		var activeMissions *store.ActiveMissionError
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.As(err, &activeMissions):
+			return c.JSON(http.StatusConflict, activeMissions)
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -104,6 +174,96 @@ func (app *application) deleteCatHandler(c echo.Context) error {
 	return c.JSON(http.StatusNoContent, nil)
 }
 
+// Restore cat godoc
+//
+//	@Summary		Restore spy cat
+//	@Description	Restore an archived spy cat by ID
+//	@Tags			spycat
+//	@Produce		json
+//	@Param			id	path		int	true	"Cat ID"
+//	@Success		200	{object}	store.Cat
+//	@Failure		400	{object}	error
+//	@Failure		422	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/spycat/{id}/restore [post]
+func (app *application) restoreCatHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	cat, err := app.store.Cat.RestoreSpyCat(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, cat)
+}
+
+// Purge cat godoc
+//
+//	@Summary		Purge spy cat
+//	@Description	Permanently delete an archived spy cat by ID
+//	@Tags			admin
+//	@Produce		json
+//	@Param			id	path		int	true	"Cat ID"
+//	@Success		204	{object}	nil
+//	@Failure		400	{object}	error
+//	@Failure		422	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/admin/spycat/{id} [delete]
+func (app *application) purgeCatHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	err = app.store.Cat.PurgeSpyCat(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Get cat missions godoc
+//
+//	@Summary		Get cat missions
+//	@Description	Get the past and current missions of a cat, newest assignment first
+//	@Tags			spycat
+//	@Produce		json
+//	@Param			id	path		int	true	"Cat ID"
+//	@Success		200	{object}	[]store.CatAssignment
+//	@Failure		400	{object}	error
+//	@Failure		422	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/spycat/{id}/missions [get]
+func (app *application) getCatMissionsHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	missions, err := app.store.Mission.GetCatMissions(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, missions)
+}
+
 // Get cat info godoc
 //
 //	@Summary		Get cat info
@@ -132,26 +292,57 @@ func (app *application) getCatByIDHandler(c echo.Context) error {
 
 // Update cat godoc
 //
-//	@Summary		Update cat salary
-//	@Description	Update cat salary by ID
+//	@Summary		Update cat profile
+//	@Description	Partially update cat profile by ID with an RFC 7396 merge patch. Only the fields sent are changed, null is rejected because every field is required.
+//	@Description	The breed is matched like on create, an unknown breed gets 422 with suggestions.
 //	@Tags			spycat
+//	@Accept			json
+//	@Accept			application/merge-patch+json
 //	@Produce		json
 //	@Param			id		path		int						true	"Cat ID"
 //	@Param			payload	body		UpdateCatInfoPayload	true	"Update SpyCat payload"
 //	@Success		200		{object}	store.Cat
 //	@Failure		422		{object}	error
+//	@Failure		415		{object}	error
 //	@Failure		400		{object}	error
 //	@Failure		500		{object}	error
+//	@Failure		503		{object}	error
 //	@Router			/spycat/{id} [patch]
 func (app *application) updateCatHandler(c echo.Context) error {
-	var payload UpdateCatInfoPayload
-	if err := c.Bind(&payload); err != nil {
-		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	payload, err := bindMergePatch(c.Request())
+	if err != nil {
+		switch {
+		case errors.Is(err, UnsupportedMediaType):
+			return c.JSON(http.StatusUnsupportedMediaType, err.Error())
+		case errors.Is(err, ValidationError):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
 	}
 
-	if err := Validate.Struct(payload); err != nil {
-		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	if payload.Breed != nil {
+		var breed string
+		if breed, err = app.validateCatPayload(c.Request().Context(), payload, *payload.Breed); err == nil {
+			payload.Breed = &breed
+		}
+	} else if err = Validate.Struct(payload); err != nil {
+		err = ValidationError
 	}
+	if err != nil {
+		var unknown *UnknownBreedError
+		switch {
+		case errors.As(err, &unknown):
+			return c.JSON(http.StatusUnprocessableEntity, unknown)
+		case errors.Is(err, ValidationError):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, EmptyBreedCatalog):
+			return c.JSON(http.StatusServiceUnavailable, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+
 	cat, err := app.getCatByID(c)
 	if err != nil {
 		switch {
@@ -164,15 +355,16 @@ func (app *application) updateCatHandler(c echo.Context) error {
 		}
 	}
 
-	updatedCat := &store.Cat{
-		ID:         cat.ID,
-		Name:       cat.Name,
-		Experience: cat.Experience,
-		Breed:      cat.Breed,
+	patch := &store.CatPatch{
+		Name:       payload.Name,
+		Experience: payload.Experience,
+		Breed:      payload.Breed,
 		Salary:     payload.Salary,
 	}
-
-	err = app.store.Cat.UpdateSpyCat(c.Request().Context(), updatedCat)
+	if payload.SalaryReason != nil {
+		patch.SalaryReason = *payload.SalaryReason
+	}
+	updatedCat, err := app.store.Cat.UpdateSpyCat(c.Request().Context(), cat.ID, patch)
 	if err != nil {
 		switch err {
 		case store.ErrNotFound:
@@ -187,26 +379,76 @@ func (app *application) updateCatHandler(c echo.Context) error {
 // Get cat list
 //
 //	@Summary		Fetches spy cat list
-//	@Description	Fetches spy cat list
+//	@Description	Fetches spy cat list. Offset mode returns a plain array, passing cursor returns a page envelope with next_cursor, prev_cursor and total.
 //	@Tags			spycat
 //	@Accept			json
 //	@Produce		json
-//	@Param			limit	query		int	false	"Limit"
-//	@Param			offset	query		int	false	"Offset"
-//	@Success		200		{object}	[]store.Cat
-//	@Failure		422		{object}	error
-//	@Failure		400		{object}	error
-//	@Failure		500		{object}	error
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Param			sort			query		string	false	"Sort field"	Enums(id, name, year_of_experience, breed, salary)
+//	@Param			order			query		string	false	"Sort order"	Enums(asc, desc)
+//	@Param			breed			query		string	false	"Breed (case-insensitive)"
+//	@Param			search			query		string	false	"Case-insensitive name search"
+//	@Param			min_salary		query		int		false	"Minimum salary"
+//	@Param			max_salary		query		int		false	"Maximum salary"
+//	@Param			min_experience	query		int		false	"Minimum years of experience"
+//	@Param			max_experience	query		int		false	"Maximum years of experience"
+//	@Param			include			query		string	false	"Also list archived cats"	Enums(deleted)
+//	@Param			breed_status	query		string	false	"Breed verification status"	Enums(verified, pending, rejected)
+//	@Param			cursor			query		string	false	"Opaque cursor, switches to keyset pagination (empty for the first page)"
+//	@Param			with_total		query		bool	false	"Include the total number of matching cats"
+//	@Success		200				{object}	[]store.Cat
+//	@Failure		422				{object}	error
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
 //	@Router			/spycat [get]
 func (app *application) getPaginatedCatListHandler(c echo.Context) error {
-	filterDefault := store.PaginatedQuery{
-		Limit:  10,
-		Offset: 0,
+	return app.listCats(c, false)
+}
+
+// Get available cats
+//
+//	@Summary		Fetches cats free for a mission
+//	@Description	Fetches spy cats that have no incomplete mission and whose breed was not rejected. Accepts the same filters and pagination as the cat list.
+//	@Tags			spycat
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit			query		int		false	"Limit"
+//	@Param			offset			query		int		false	"Offset"
+//	@Param			sort			query		string	false	"Sort field"	Enums(id, name, year_of_experience, breed, salary)
+//	@Param			order			query		string	false	"Sort order"	Enums(asc, desc)
+//	@Param			breed			query		string	false	"Breed (case-insensitive)"
+//	@Param			search			query		string	false	"Case-insensitive name search"
+//	@Param			min_salary		query		int		false	"Minimum salary"
+//	@Param			max_salary		query		int		false	"Maximum salary"
+//	@Param			min_experience	query		int		false	"Minimum years of experience"
+//	@Param			max_experience	query		int		false	"Maximum years of experience"
+//	@Param			breed_status	query		string	false	"Breed verification status"	Enums(verified, pending)
+//	@Param			cursor			query		string	false	"Opaque cursor, switches to keyset pagination (empty for the first page)"
+//	@Param			with_total		query		bool	false	"Include the total number of matching cats"
+//	@Success		200				{object}	[]store.Cat
+//	@Failure		422				{object}	error
+//	@Failure		400				{object}	error
+//	@Failure		500				{object}	error
+//	@Router			/spycat/available [get]
+func (app *application) getAvailableCatsHandler(c echo.Context) error {
+	return app.listCats(c, true)
+}
+
+func (app *application) listCats(c echo.Context, available bool) error {
+	filterDefault := store.CatListQuery{
+		PaginatedQuery: store.PaginatedQuery{
+			Limit:  10,
+			Offset: 0,
+		},
+		Sort:  "id",
+		Order: "asc",
 	}
 	filterQuery, err := filterDefault.Parse(c.Request())
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, ValidationError.Error())
 	}
+	filterQuery.Available = available
 
 	if err = Validate.Struct(filterQuery); err != nil {
 		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
@@ -214,9 +456,14 @@ func (app *application) getPaginatedCatListHandler(c echo.Context) error {
 
 	cats, err := app.store.Cat.GetPaginatedSpyCatList(c.Request().Context(), filterQuery)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			return c.JSON(http.StatusBadRequest, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
 	}
-	return c.JSON(http.StatusOK, cats)
+	return writePage(c, filterQuery.PaginatedQuery, cats)
 }
 
 func (app *application) getCatByID(c echo.Context) (*store.Cat, error) {
@@ -237,3 +484,46 @@ func (app *application) getCatByID(c echo.Context) (*store.Cat, error) {
 	}
 	return cat, nil
 }
+
+// validateCatPayload validates a cat payload and returns its breed as the
+// canonical catalog name.
+func (app *application) validateCatPayload(ctx context.Context, payload any, breed string) (string, error) {
+	if err := Validate.StructExceptCtx(ctx, payload, "Breed"); err != nil {
+		return "", ValidationError
+	}
+	return app.canonicalBreed(ctx, breed)
+}
+
+// bindMergePatch decodes an RFC 7396 merge patch body. Every cat field is
+// required, so removing one with null is a validation error.
+func bindMergePatch(r *http.Request) (*UpdateCatInfoPayload, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(echo.HeaderContentType))
+	if mediaType != mimeMergePatchJSON && mediaType != echo.MIMEApplicationJSON {
+		return nil, UnsupportedMediaType
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	var members map[string]json.RawMessage
+	if err = json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	for name, value := range members {
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			return nil, fmt.Errorf("%w: %s cannot be removed", ValidationError, name)
+		}
+	}
+
+	payload := &UpdateCatInfoPayload{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(payload); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return nil, fmt.Errorf("%w: %s", ValidationError, strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client.
+const exportFlushEvery = 100
+
+var NotAcceptable = errors.New("not acceptable")
+
+// exportEncoder writes one row at a time in the negotiated format.
+type exportEncoder interface {
+	Encode(record []string, value any) error
+	Flush() error
+}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	flusher http.Flusher
+}
+
+func (e *csvEncoder) Encode(record []string, _ any) error {
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	e.flusher.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+func (e *ndjsonEncoder) Encode(_ []string, value any) error {
+	return e.enc.Encode(value)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	e.flusher.Flush()
+	return nil
+}
+
+// Export godoc
+//
+//	@Summary		Export cats, missions or targets
+//	@Description	Stream every row of a resource as CSV or NDJSON, picked from the Accept header (CSV by default).
+//	@Description	Cats accept the same filters and sort options as the cat list, missions and targets the same filters as their lists. Responses are gzipped when the client accepts it.
+//	@Tags			export
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Param			resource		path		string	true	"What to export"		Enums(cats, missions, targets)
+//	@Param			sort			query		string	false	"Sort field for cats"	Enums(id, name, year_of_experience, breed, salary)
+//	@Param			order			query		string	false	"Sort order for cats"	Enums(asc, desc)
+//	@Param			breed			query		string	false	"Breed (case-insensitive)"
+//	@Param			search			query		string	false	"Case-insensitive name search of cats or targets"
+//	@Param			min_salary		query		int		false	"Minimum salary"
+//	@Param			max_salary		query		int		false	"Maximum salary"
+//	@Param			min_experience	query		int		false	"Minimum years of experience"
+//	@Param			max_experience	query		int		false	"Maximum years of experience"
+//	@Param			include			query		string	false	"Also export archived cats"	Enums(deleted)
+//	@Param			breed_status	query		string	false	"Breed verification status"	Enums(verified, pending, rejected)
+//	@Param			status			query		string	false	"Mission lifecycle state"	Enums(draft, assigned, in_progress, completed, aborted, failed)
+//	@Param			completed		query		bool	false	"Finished missions or completed targets"
+//	@Param			assigned		query		bool	false	"Missions with or without a cat"
+//	@Param			overdue			query		bool	false	"Missions marked overdue or not"
+//	@Param			cat_id			query		int		false	"Cat ID of the missions"
+//	@Param			country			query		string	false	"Target country, for missions of any of their targets (case-insensitive)"
+//	@Success		200				{string}	string
+//	@Failure		400				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		406				{object}	error
+//	@Failure		422				{object}	error
+//	@Router			/export/{resource} [get]
+func (app *application) exportHandler(c echo.Context) error {
+	mediaType, err := negotiateExportFormat(c.Request().Header.Get(echo.HeaderAccept))
+	if err != nil {
+		return c.JSON(http.StatusNotAcceptable, err.Error())
+	}
+
+	resource := c.Param("resource")
+	var catFilter store.CatListQuery
+	var missionFilter store.MissionListQuery
+	var targetFilter store.TargetListQuery
+	switch resource {
+	case "cats":
+		catFilter, err = store.CatListQuery{
+			PaginatedQuery: store.PaginatedQuery{Limit: 1},
+			Sort:           "id",
+			Order:          "asc",
+		}.Parse(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
+		if err = Validate.StructExcept(catFilter, "PaginatedQuery"); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+		}
+	case "missions":
+		missionFilter, err = store.MissionListQuery{}.Parse(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
+		if err = Validate.StructExcept(missionFilter, "PaginatedQuery", "Include"); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+		}
+	case "targets":
+		targetFilter, err = store.TargetListQuery{}.Parse(c.Request())
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
+		if err = Validate.StructExcept(targetFilter, "PaginatedQuery"); err != nil {
+			return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+		}
+	default:
+		return c.JSON(http.StatusNotFound, store.ErrNotFound.Error())
+	}
+
+	extension := "csv"
+	if mediaType == mimeNDJSON {
+		extension = "ndjson"
+	}
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, mediaType)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, resource, extension))
+	res.WriteHeader(http.StatusOK)
+
+	enc := newExportEncoder(res, mediaType)
+	rows := 0
+	write := func(record []string, value any) error {
+		if err := enc.Encode(record, value); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			return enc.Flush()
+		}
+		return nil
+	}
+
+	header := func(columns ...string) error {
+		if mediaType != mimeTextCSV {
+			return nil
+		}
+		return enc.Encode(columns, nil)
+	}
+
+	ctx := c.Request().Context()
+	switch resource {
+	case "cats":
+		err = header("id", "name", "year_of_experience", "breed", "breed_status", "salary", "deleted_at")
+		if err == nil {
+			err = app.store.Cat.ExportSpyCats(ctx, catFilter, func(cat *store.Cat) error {
+				return write(catRecord(cat), cat)
+			})
+		}
+	case "missions":
+		err = header("id", "status", "completed", "cat_id", "cat_name", "starts_at", "deadline", "overdue_at")
+		if err == nil {
+			err = app.store.Mission.ExportMissions(ctx, missionFilter, func(m *store.MissionWithMetadata) error {
+				return write(missionRecord(m), m)
+			})
+		}
+	case "targets":
+		err = header("id", "mission_id", "name", "country", "notes", "completed", "starts_at", "deadline")
+		if err == nil {
+			err = app.store.Target.ExportTargets(ctx, targetFilter, func(t *store.Target) error {
+				return write(targetRecord(t), t)
+			})
+		}
+	}
+	if err == nil {
+		err = enc.Flush()
+	}
+	if err != nil {
+		// The status line is already sent, all that is left is to log and cut
+		// the stream short.
+		app.logger.Errorw("export failed", "resource", resource, "rows", rows, "error", err)
+	}
+	return nil
+}
+
+// negotiateExportFormat picks CSV or NDJSON from an Accept header. A missing
+// header or a wildcard gets CSV.
+func negotiateExportFormat(accept string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return mimeTextCSV, nil
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case mimeTextCSV, "text/*", "*/*":
+			return mimeTextCSV, nil
+		case mimeNDJSON:
+			return mimeNDJSON, nil
+		}
+	}
+	return "", NotAcceptable
+}
+
+func newExportEncoder(res *echo.Response, mediaType string) exportEncoder {
+	if mediaType == mimeNDJSON {
+		return &ndjsonEncoder{enc: json.NewEncoder(io.Writer(res)), flusher: res}
+	}
+	return &csvEncoder{w: csv.NewWriter(res), flusher: res}
+}
+
+func catRecord(cat *store.Cat) []string {
+	return []string{
+		strconv.FormatInt(cat.ID, 10),
+		cat.Name,
+		strconv.Itoa(cat.Experience),
+		cat.Breed,
+		cat.BreedStatus,
+		strconv.Itoa(cat.Salary),
+		formatTime(cat.DeletedAt),
+	}
+}
+
+func missionRecord(m *store.MissionWithMetadata) []string {
+	catID, catName := "", ""
+	if m.Cat != nil {
+		catID, catName = strconv.FormatInt(m.Cat.ID, 10), m.Cat.Name
+	}
+	return []string{
+		strconv.FormatInt(m.Mission.ID, 10),
+		m.Mission.Status,
+		strconv.FormatBool(m.Mission.Completed),
+		catID,
+		catName,
+		formatTime(m.Mission.StartsAt),
+		formatTime(m.Mission.Deadline),
+		formatTime(m.Mission.OverdueAt),
+	}
+}
+
+func targetRecord(t *store.Target) []string {
+	return []string{
+		strconv.FormatInt(t.ID, 10),
+		strconv.FormatInt(t.MissionID, 10),
+		t.Name,
+		t.Country,
+		t.Notes,
+		strconv.FormatBool(t.Completed),
+		formatTime(t.StartsAt),
+		formatTime(t.Deadline),
+	}
+}
+
+// formatTime renders an optional timestamp as RFC 3339, or empty when unset.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
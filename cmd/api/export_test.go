@@ -0,0 +1,29 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiateExportFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		err    error
+	}{
+		{"", mimeTextCSV, nil},
+		{"*/*", mimeTextCSV, nil},
+		{"text/*", mimeTextCSV, nil},
+		{"text/csv", mimeTextCSV, nil},
+		{"application/x-ndjson", mimeNDJSON, nil},
+		{"application/xml, application/x-ndjson", mimeNDJSON, nil},
+		{"invalid;;, text/csv", mimeTextCSV, nil},
+		{"application/json", "", NotAcceptable},
+	}
+	for _, tt := range tests {
+		got, err := negotiateExportFormat(tt.accept)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("negotiateExportFormat(%q) = %q, %v, want %q, %v", tt.accept, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"FIDOtestBackendApp/internal/store/cache"
 	"github.com/labstack/echo/v4"
 	"net/http"
 )
@@ -20,5 +21,20 @@ func (app *application) healthCheckHandler(c echo.Context) error {
 		"status":  "OK",
 		"env":     app.config.env,
 	}
+	if app.breedAPI != nil {
+		data["breeds"] = string(app.breedAPI.State())
+	}
 	return c.JSON(http.StatusOK, data)
 }
+
+// Cache stats godoc
+//
+//	@Summary		Record cache counters
+//	@Description	Hit and miss counts of the cat and mission cache, e.g. cat_hits and mission_misses
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	map[string]int64
+//	@Router			/admin/cache/stats [get]
+func (app *application) cacheStatsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, cache.RecordStats())
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	mimeTextCSV = "text/csv"
+	mimeNDJSON  = "application/x-ndjson"
+
+	importModeAtomic = "atomic"
+	importModeSkip   = "skip"
+
+	maxImportRows  = 1000
+	maxImportBytes = 5 << 20
+)
+
+var csvImportColumns = []string{"name", "year_of_experience", "breed", "salary"}
+
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+}
+
+type ImportResult struct {
+	Mode     string           `json:"mode"`
+	Total    int              `json:"total"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	IDs      []int64          `json:"ids"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+type importRow struct {
+	line    int
+	payload CreateCatPayload
+	err     error
+}
+
+// Import cats godoc
+//
+//	@Summary		Bulk import spy cats
+//	@Description	Import spy cats from CSV (header name,year_of_experience,breed,salary) or NDJSON.
+//	@Description	Every row is validated like POST /spycat and all valid rows are inserted in one transaction.
+//	@Description	In atomic mode any invalid row aborts the import, in skip mode invalid rows are reported and left out.
+//	@Tags			spycat
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			mode	query		string	false	"Import mode"	Enums(atomic, skip)	default(atomic)
+//	@Success		201		{object}	ImportResult
+//	@Failure		400		{object}	error
+//	@Failure		413		{object}	error
+//	@Failure		415		{object}	error
+//	@Failure		422		{object}	ImportResult
+//	@Failure		500		{object}	error
+//	@Failure		503		{object}	error
+//	@Router			/spycat/import [post]
+func (app *application) importCatsHandler(c echo.Context) error {
+	mode := c.QueryParam("mode")
+	if mode == "" {
+		mode = importModeAtomic
+	}
+	if mode != importModeAtomic && mode != importModeSkip {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxImportBytes)
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+
+	var rows []importRow
+	var err error
+	switch mediaType {
+	case mimeTextCSV:
+		rows, err = readCSVImport(body)
+	case mimeNDJSON:
+		rows, err = readNDJSONImport(body)
+	default:
+		return c.JSON(http.StatusUnsupportedMediaType, UnsupportedMediaType.Error())
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("import is limited to %d bytes", tooLarge.Limit))
+		}
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	result := &ImportResult{Mode: mode, Total: len(rows), IDs: []int64{}, Errors: []ImportRowError{}}
+	breeds := make(map[string]breedResolution)
+	var cats []*store.Cat
+	for i := range rows {
+		row := &rows[i]
+		messages, err := app.validateImportRow(ctx, row, breeds)
+		if err != nil {
+			if errors.Is(err, EmptyBreedCatalog) {
+				return c.JSON(http.StatusServiceUnavailable, err.Error())
+			}
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+		if len(messages) > 0 {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.line, Errors: messages})
+			continue
+		}
+		cats = append(cats, &store.Cat{
+			Name:       row.payload.Name,
+			Breed:      row.payload.Breed,
+			Experience: row.payload.Experience,
+			Salary:     row.payload.Salary,
+		})
+	}
+	result.Failed = len(result.Errors)
+
+	if mode == importModeAtomic && result.Failed > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+
+	if len(cats) > 0 {
+		if err = app.store.Cat.CreateSpyCats(ctx, cats); err != nil {
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	for _, cat := range cats {
+		result.IDs = append(result.IDs, cat.ID)
+	}
+	result.Imported = len(cats)
+	return c.JSON(http.StatusCreated, result)
+}
+
+// breedResolution is the outcome of resolving one breed during an import.
+type breedResolution struct {
+	name    string
+	unknown *UnknownBreedError
+	invalid bool
+}
+
+// validateImportRow returns the problems of one row and puts the canonical
+// breed name into the row. Breeds are resolved once per import and
+// remembered in breeds.
+func (app *application) validateImportRow(ctx context.Context, row *importRow, breeds map[string]breedResolution) ([]string, error) {
+	if row.err != nil {
+		return []string{row.err.Error()}, nil
+	}
+
+	var messages []string
+	if err := Validate.StructExcept(row.payload, "Breed"); err != nil {
+		messages = append(messages, validationMessages(err)...)
+	}
+
+	if row.payload.Breed == "" {
+		return append(messages, "Breed: failed on required"), nil
+	}
+	resolved, checked := breeds[row.payload.Breed]
+	if !checked {
+		canonical, err := app.canonicalBreed(ctx, row.payload.Breed)
+		var unknown *UnknownBreedError
+		switch {
+		case err == nil:
+			resolved = breedResolution{name: canonical}
+		case errors.As(err, &unknown):
+			resolved = breedResolution{unknown: unknown}
+		case errors.Is(err, ValidationError):
+			resolved = breedResolution{invalid: true}
+		default:
+			return nil, err
+		}
+		breeds[row.payload.Breed] = resolved
+	}
+	switch {
+	case resolved.unknown != nil:
+		message := fmt.Sprintf("Breed: unknown breed %q", row.payload.Breed)
+		if len(resolved.unknown.Suggestions) > 0 {
+			message += fmt.Sprintf(", did you mean %s?", strings.Join(resolved.unknown.Suggestions, ", "))
+		}
+		messages = append(messages, message)
+	case resolved.invalid:
+		messages = append(messages, "Breed: failed on max")
+	case len(messages) == 0:
+		row.payload.Breed = resolved.name
+	}
+	return messages, nil
+}
+
+func validationMessages(err error) []string {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []string{err.Error()}
+	}
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		messages = append(messages, fmt.Sprintf("%s: failed on %s", fe.Field(), fe.Tag()))
+	}
+	return messages
+}
+
+func readCSVImport(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range csvImportColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("csv header is missing column %q", column)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+		if parseErr != nil {
+			rows = append(rows, importRow{line: parseErr.Line, err: parseErr.Err})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line}
+		row.payload.Name = record[index["name"]]
+		row.payload.Breed = record[index["breed"]]
+		if row.payload.Experience, err = strconv.Atoi(strings.TrimSpace(record[index["year_of_experience"]])); err != nil {
+			row.err = errors.New("year_of_experience: not a number")
+		} else if row.payload.Salary, err = strconv.Atoi(strings.TrimSpace(record[index["salary"]])); err != nil {
+			row.err = errors.New("salary: not a number")
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readNDJSONImport(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		row := importRow{line: line}
+		if err := json.Unmarshal(data, &row.payload); err != nil {
+			row.err = fmt.Errorf("invalid json: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCSVImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		rows    []CreateCatPayload
+		lines   []int
+		rowErrs []bool
+		err     bool
+	}{
+		{
+			name:  "columns in any order",
+			body:  "salary, Name,breed,year_of_experience\n1000,Tom,Siamese,3\n2000,Kitty,Bengal,5\n",
+			rows:  []CreateCatPayload{{Name: "Tom", Breed: "Siamese", Experience: 3, Salary: 1000}, {Name: "Kitty", Breed: "Bengal", Experience: 5, Salary: 2000}},
+			lines: []int{2, 3},
+		},
+		{
+			name:    "bad numbers are row errors",
+			body:    "name,year_of_experience,breed,salary\nTom,three,Siamese,1000\nKitty,5,Bengal,lots\n",
+			rows:    []CreateCatPayload{{Name: "Tom", Breed: "Siamese"}, {Name: "Kitty", Breed: "Bengal", Experience: 5}},
+			lines:   []int{2, 3},
+			rowErrs: []bool{true, true},
+		},
+		{
+			name:    "malformed rows are row errors",
+			body:    "name,year_of_experience,breed,salary\nTom,3,Siamese\nKitty,5,Bengal,2000\n",
+			rows:    []CreateCatPayload{{}, {Name: "Kitty", Breed: "Bengal", Experience: 5, Salary: 2000}},
+			lines:   []int{2, 3},
+			rowErrs: []bool{true, false},
+		},
+		{
+			name: "missing column",
+			body: "name,breed,salary\nTom,Siamese,1000\n",
+			err:  true,
+		},
+		{
+			name: "empty body",
+			body: "",
+			err:  true,
+		},
+		{
+			name: "too many rows",
+			body: "name,year_of_experience,breed,salary\n" + strings.Repeat("Tom,3,Siamese,1000\n", maxImportRows+1),
+			err:  true,
+		},
+		{
+			name: "too many malformed rows",
+			body: "name,year_of_experience,breed,salary\n" + strings.Repeat("Tom\n", maxImportRows+1),
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readCSVImport(strings.NewReader(tt.body))
+			checkImportRows(t, rows, err, tt.rows, tt.lines, tt.rowErrs, tt.err)
+		})
+	}
+}
+
+func TestReadNDJSONImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		rows    []CreateCatPayload
+		lines   []int
+		rowErrs []bool
+		err     bool
+	}{
+		{
+			name:  "blank lines are skipped",
+			body:  `{"name":"Tom","year_of_experience":3,"breed":"Siamese","salary":1000}` + "\n\n" + `{"name":"Kitty","breed":"Bengal"}`,
+			rows:  []CreateCatPayload{{Name: "Tom", Breed: "Siamese", Experience: 3, Salary: 1000}, {Name: "Kitty", Breed: "Bengal"}},
+			lines: []int{1, 3},
+		},
+		{
+			name:    "invalid json is a row error",
+			body:    "{not json}\n" + `{"name":"Kitty"}`,
+			rows:    []CreateCatPayload{{}, {Name: "Kitty"}},
+			lines:   []int{1, 2},
+			rowErrs: []bool{true, false},
+		},
+		{
+			name: "too many rows",
+			body: strings.Repeat("{}\n", maxImportRows+1),
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readNDJSONImport(strings.NewReader(tt.body))
+			checkImportRows(t, rows, err, tt.rows, tt.lines, tt.rowErrs, tt.err)
+		})
+	}
+}
+
+func checkImportRows(t *testing.T, rows []importRow, err error, payloads []CreateCatPayload, lines []int, rowErrs []bool, wantErr bool) {
+	t.Helper()
+	if wantErr {
+		if err == nil {
+			t.Fatalf("got %d rows, want an error", len(rows))
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != len(payloads) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(payloads))
+	}
+	for i, row := range rows {
+		if row.line != lines[i] {
+			t.Errorf("row %d: line = %d, want %d", i, row.line, lines[i])
+		}
+		wantRowErr := rowErrs != nil && rowErrs[i]
+		if (row.err != nil) != wantRowErr {
+			t.Errorf("row %d: error = %v, want error %v", i, row.err, wantRowErr)
+		}
+		if !wantRowErr && row.payload != payloads[i] {
+			t.Errorf("row %d: payload = %+v, want %+v", i, row.payload, payloads[i])
+		}
+	}
+}
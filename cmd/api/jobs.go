@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runPeriodically calls job every interval until ctx is cancelled. Failures
+// are logged and the job is retried on the next tick. An interval of zero or
+// less disables the job.
+func (app *application) runPeriodically(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	if interval <= 0 {
+		app.logger.Infow("background job disabled", "job", name, "interval", interval)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil {
+				app.logger.Errorw("background job failed", "job", name, "error", err)
+			}
+		}
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"FIDOtestBackendApp/internal/graphql"
 	"FIDOtestBackendApp/internal/store"
 	"FIDOtestBackendApp/internal/store/cache"
+	"FIDOtestBackendApp/internal/validation"
+	"context"
 	"errors"
-	"github.com/go-redis/redis/v8"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"log"
+	"time"
 )
 
 const version = "0.0.1"
@@ -45,10 +47,30 @@ func main() {
 			maxIdleTime:        env.GetString("maxIdleTime", "15m"),
 		},
 		redisConfig: redisConfig{
-			addr:     env.GetString("REDIS_ADDR", "localhost:6379"),
-			password: env.GetString("REDIS_PASSWORD", ""),
-			db:       0,
-			enabled:  true,
+			addr:           env.GetString("REDIS_ADDR", "localhost:6379"),
+			password:       env.GetString("REDIS_PASSWORD", ""),
+			db:             0,
+			enabled:        env.GetString("CACHE_BACKEND", "redis") == "redis",
+			prefix:         env.GetString("REDIS_KEY_PREFIX", "spycat"),
+			memoryCapacity: env.GetInt("CACHE_MEMORY_CAPACITY", 10000),
+			breeds: cacheTTLConfig{
+				valid:   env.GetDuration("BREED_CACHE_VALID_TTL", time.Hour),
+				invalid: env.GetDuration("BREED_CACHE_INVALID_TTL", 5*time.Minute),
+			},
+			recordTTL: env.GetDuration("RECORD_CACHE_TTL", 5*time.Minute),
+		},
+		jobs: jobsConfig{
+			salaryInterval:  env.GetDuration("SALARY_SCHEDULER_INTERVAL", time.Minute),
+			overdueInterval: env.GetDuration("MISSION_OVERDUE_INTERVAL", time.Minute),
+		},
+		breeds: breedsConfig{
+			provider:       env.GetString("BREED_PROVIDER", "thecatapi"),
+			file:           env.GetString("BREEDS_FILE", ""),
+			static:         env.GetString("BREEDS_STATIC", ""),
+			apiURL:         env.GetString("BREEDS_API_URL", "https://api.thecatapi.com/v1/breeds"),
+			syncInterval:   env.GetDuration("BREEDS_SYNC_INTERVAL", 24*time.Hour),
+			cacheTTL:       env.GetDuration("BREEDS_CACHE_TTL", time.Hour),
+			verifyInterval: env.GetDuration("BREED_VERIFY_INTERVAL", time.Minute),
 		},
 	}
 
@@ -69,14 +91,30 @@ func main() {
 
 	// Storage init
 	storage := store.NewStorage(database)
+	validation.RegisterCatValidator(Validate, storage.Breed)
 
-	//redis
-	var cacheRedis *redis.Client
+	// Cache init, an in-process LRU stands in when Redis is disabled
+	var cacheBackend cache.Backend
 	if cfg.redisConfig.enabled {
-		cacheRedis = cache.NewRedisClient(cfg.redisConfig.addr, cfg.redisConfig.password, cfg.redisConfig.db)
+		cacheBackend = cache.NewRedisBackend(cache.NewRedisClient(cfg.redisConfig.addr, cfg.redisConfig.password, cfg.redisConfig.db))
+	} else {
+		cacheBackend = cache.NewMemoryBackend(cfg.redisConfig.memoryCapacity)
 	}
-	cacheStorage := cache.NewRedisStorage(cacheRedis)
-	graphqlStorage := graphql.NewGPQLStorage()
+	cacheStorage := cache.NewStorage(cacheBackend, cache.BreedCacheConfig{
+		Prefix:     cfg.redisConfig.prefix,
+		ValidTTL:   cfg.redisConfig.breeds.valid,
+		InvalidTTL: cfg.redisConfig.breeds.invalid,
+	})
+	if cfg.redisConfig.recordTTL > 0 {
+		storage = cache.NewCachedStorage(storage, cacheBackend, cache.RecordCacheConfig{
+			Prefix: cfg.redisConfig.prefix,
+			TTL:    cfg.redisConfig.recordTTL,
+			OnError: func(err error) {
+				logger.Warnw("record cache failed", "error", err)
+			},
+		})
+	}
+	graphqlStorage := graphql.NewGPQLStorage(database)
 	app := &application{
 		config:         cfg,
 		logger:         logger,
@@ -84,6 +122,25 @@ func main() {
 		cacheStorage:   cacheStorage,
 		graphqlStorage: graphqlStorage,
 	}
+	app.breedProvider, app.breedAPI, err = newBreedProvider(cfg.breeds)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.runPeriodically(ctx, "salary scheduler", cfg.jobs.salaryInterval, app.applyScheduledSalaries)
+	go app.runPeriodically(ctx, "overdue missions", cfg.jobs.overdueInterval, app.markOverdueMissions)
+	go func() {
+		// Fill the catalog right away, the first tick may be a day out.
+		if err := app.syncBreeds(ctx); err != nil {
+			logger.Errorw("initial breed sync failed", "error", err)
+		}
+		app.runPeriodically(ctx, "breed sync", cfg.breeds.syncInterval, app.syncBreeds)
+	}()
+	go app.runPeriodically(ctx, "breed verification", cfg.breeds.verifyInterval, app.verifyPendingBreeds)
+
 	mux := app.mount()
 	log.Fatal(app.run(mux))
 }
@@ -2,22 +2,67 @@ package main
 
 import (
 	"FIDOtestBackendApp/internal/store"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+type ReassignPayload struct {
+	MissionID int64  `json:"mission_id" validate:"required,gte=1"`
+	Reason    string `json:"reason" validate:"max=255"`
+}
+
+type TransitionPayload struct {
+	Status string `json:"status" validate:"required,oneof=draft assigned in_progress completed aborted failed"`
+	Reason string `json:"reason" validate:"max=255"`
+}
+
 type MissionPayload struct {
-	Complete *bool    `json:"complete" validate:"required"`
-	Targets  []Target `json:"targets" validate:"required,min=1,max=3,dive"`
+	Complete *bool      `json:"complete" validate:"required"`
+	StartsAt *time.Time `json:"starts_at"`
+	Deadline *time.Time `json:"deadline"`
+	Targets  []Target   `json:"targets" validate:"required,min=1,max=3,dive"`
+}
+
+// SchedulePayload is a merge patch of a time window. Members left out keep
+// their value, null clears one.
+type SchedulePayload struct {
+	StartsAt *time.Time `json:"starts_at"`
+	Deadline *time.Time `json:"deadline"`
+}
+
+// bindSchedulePatch decodes a SchedulePayload and records which members the
+// body contains.
+func bindSchedulePatch(r *http.Request) (store.SchedulePatch, error) {
+	var patch store.SchedulePatch
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&members); err != nil {
+		return patch, err
+	}
+	if value, ok := members["starts_at"]; ok {
+		if err := json.Unmarshal(value, &patch.StartsAt); err != nil {
+			return patch, err
+		}
+		patch.SetStartsAt = true
+	}
+	if value, ok := members["deadline"]; ok {
+		if err := json.Unmarshal(value, &patch.Deadline); err != nil {
+			return patch, err
+		}
+		patch.SetDeadline = true
+	}
+	return patch, nil
 }
 
 // Create Mission
 //
 //	@Summary		Create Mission
-//	@Description	Create new Mission
+//	@Description	Create new Mission. It starts as draft, complete has to be false. The optional starts_at and deadline of the mission and its targets are checked: deadlines have to be in the future and after starts_at, target windows have to fit into the mission's.
 //	@Tags			mission
 //	@Accept			json
 //	@Produce		json
@@ -51,6 +96,7 @@ func (app *application) createMissionHandler(c echo.Context) error {
 			Country:   target.Country,
 			Notes:     target.Notes,
 			Completed: *target.Complete,
+			Schedule:  store.Schedule{StartsAt: target.StartsAt, Deadline: target.Deadline},
 		})
 	}
 
@@ -59,13 +105,21 @@ func (app *application) createMissionHandler(c echo.Context) error {
 		Mission: store.Mission{
 			CatID:     nil,
 			Completed: *payload.Complete,
+			Schedule:  store.Schedule{StartsAt: payload.StartsAt, Deadline: payload.Deadline},
 		},
 	}
+	schedules := []store.Schedule{mission.Mission.Schedule}
+	for _, target := range targets {
+		schedules = append(schedules, target.Schedule)
+	}
+	if err := futureDeadline(schedules...); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
 
 	err := app.store.Mission.CreateMission(c.Request().Context(), mission)
 	if err != nil {
-		switch err {
-		case store.ViolatePK:
+		switch {
+		case errors.Is(err, store.ViolatePK), errors.Is(err, store.ErrInvalidSchedule), errors.Is(err, store.ErrInvalidTransition):
 			return c.JSON(http.StatusUnprocessableEntity, err.Error())
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
@@ -111,12 +165,13 @@ func (app *application) deleteMissionHandler(c echo.Context) error {
 // Update mission
 //
 //	@Summary		Update mission
-//	@Description	Update mission by ID
+//	@Description	Mark the mission completed. The mission is moved through its lifecycle, draft and finished missions cannot be completed this way.
 //	@Tags			mission
 //	@Produce		json
 //	@Param			id	path		int	true	"Mission ID"
 //	@Success		200	{object}	store.UpdatedMission
 //	@Failure		422	{object}	error
+//	@Failure		409	{object}	store.TransitionError
 //	@Failure		400	{object}	error
 //	@Failure		500	{object}	error
 //	@Router			/mission/{id} [patch]
@@ -132,9 +187,12 @@ func (app *application) updateMissionStatus(c echo.Context) error {
 	}
 	err = app.store.Mission.UpdateMissionStatus(c.Request().Context(), payload)
 	if err != nil {
+		var transitionErr *store.TransitionError
 		switch {
 		case errors.Is(err, store.ErrNotFound):
 			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.As(err, &transitionErr):
+			return c.JSON(http.StatusConflict, transitionErr)
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -142,15 +200,120 @@ func (app *application) updateMissionStatus(c echo.Context) error {
 	return c.JSON(http.StatusOK, payload)
 }
 
+// Update mission schedule
+//
+//	@Summary		Update mission schedule
+//	@Description	Change the starts_at and deadline of an unfinished mission. Members left out keep their value, null clears one. A changed deadline has to be in the future, an unchanged one may have passed. The deadline has to leave room for the target windows. Moving the deadline clears the overdue mark.
+//	@Tags			mission
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Mission ID"
+//	@Param			payload	body		SchedulePayload	true	"Mission schedule"
+//	@Success		200		{object}	store.Mission
+//	@Failure		422		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/mission/{id}/schedule [patch]
+func (app *application) updateMissionSchedule(c echo.Context) error {
+	missionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	patch, err := bindSchedulePatch(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	mission, err := app.store.Mission.UpdateMissionSchedule(c.Request().Context(), missionID, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrInvalidSchedule):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.MissionCompleted):
+			return c.JSON(http.StatusConflict, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, mission)
+}
+
+// futureDeadline rejects deadlines that have already passed.
+func futureDeadline(schedules ...store.Schedule) error {
+	for _, schedule := range schedules {
+		if schedule.Deadline != nil && !schedule.Deadline.After(time.Now()) {
+			return errors.New("deadline must be in the future")
+		}
+	}
+	return nil
+}
+
+// markOverdueMissions is the background job that flags unfinished missions
+// past their deadline.
+func (app *application) markOverdueMissions(ctx context.Context) error {
+	ids, err := app.store.Mission.MarkOverdueMissions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		app.logger.Infow("marked missions overdue", "count", len(ids), "mission_ids", ids)
+	}
+	return nil
+}
+
+// Transition mission
+//
+//	@Summary		Transition mission
+//	@Description	Move the mission to another lifecycle state: draft -> assigned | aborted, assigned -> in_progress | draft | aborted, in_progress -> completed | failed | aborted. Completed, aborted and failed missions are frozen. Assigned and in_progress need a cat, moving back to draft takes the cat off.
+//	@Tags			mission
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"Mission ID"
+//	@Param			payload	body		TransitionPayload	true	"Target state"
+//	@Success		200		{object}	store.Mission
+//	@Failure		422		{object}	error
+//	@Failure		409		{object}	store.TransitionError
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/mission/{id}/transition [post]
+func (app *application) transitionMission(c echo.Context) error {
+	missionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	var payload TransitionPayload
+	if err = c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if err = Validate.Struct(payload); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	mission, err := app.store.Mission.TransitionMission(c.Request().Context(), missionID, payload.Status, payload.Reason)
+	if err != nil {
+		var transitionErr *store.TransitionError
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.As(err, &transitionErr):
+			return c.JSON(http.StatusConflict, transitionErr)
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, mission)
+}
+
 // Add Spy Cat to Mission
 //
 //	@Summary		Add Spy Cat to Mission
-//	@Description	Add Spy Cat to Mission
+//	@Description	Add Spy Cat to Mission. Cats whose breed was rejected by verification cannot be assigned.
 //	@Tags			mission
 //	@Param			id		path		int	true	"Mission ID"
 //	@Param			cat_id	path		int	true	"Cat ID"
 //	@Success		204		{object}	nil
 //	@Failure		422		{object}	error
+//	@Failure		409		{object}	error
 //	@Failure		400		{object}	error
 //	@Failure		500		{object}	error
 //	@Router			/mission/{id}/cat/{cat_id} [patch]
@@ -174,6 +337,8 @@ func (app *application) addCatToMission(c echo.Context) error {
 			return c.JSON(http.StatusConflict, err.Error())
 		case errors.Is(err, store.ViolatePK):
 			return c.JSON(http.StatusConflict, err.Error())
+		case errors.Is(err, store.BreedRejected):
+			return c.JSON(http.StatusConflict, err.Error())
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -182,35 +347,147 @@ func (app *application) addCatToMission(c echo.Context) error {
 	return c.NoContent(http.StatusCreated)
 }
 
+// Unassign Spy Cat from Mission
+//
+//	@Summary		Unassign Spy Cat from Mission
+//	@Description	Take the cat off an incomplete mission
+//	@Tags			mission
+//	@Param			id		path		int		true	"Mission ID"
+//	@Param			reason	query		string	false	"Why the cat was unassigned"
+//	@Success		204		{object}	nil
+//	@Failure		422		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/mission/{id}/cat [delete]
+func (app *application) unassignCatFromMission(c echo.Context) error {
+	missionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	reason := c.QueryParam("reason")
+	if err = Validate.Var(reason, "max=255"); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	err = app.store.Mission.UnassignCat(c.Request().Context(), missionID, reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.MissionCompleted):
+			return c.JSON(http.StatusConflict, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Reassign Spy Cat
+//
+//	@Summary		Reassign Spy Cat to another Mission
+//	@Description	Move the cat of this mission to another incomplete mission in one step. The target has to be a different mission. Cats whose breed was rejected by verification cannot be moved to another mission.
+//	@Tags			mission
+//	@Accept			json
+//	@Param			id		path		int				true	"Mission ID the cat is moved from"
+//	@Param			payload	body		ReassignPayload	true	"Target mission"
+//	@Success		204		{object}	nil
+//	@Failure		422		{object}	error
+//	@Failure		409		{object}	error
+//	@Failure		400		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/mission/{id}/reassign [post]
+func (app *application) reassignCat(c echo.Context) error {
+	missionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	var payload ReassignPayload
+	if err = c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if err = Validate.Struct(payload); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	err = app.store.Mission.ReassignCat(c.Request().Context(), missionID, payload.MissionID, payload.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.SameMission):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.MissionCompleted):
+			return c.JSON(http.StatusConflict, err.Error())
+		case errors.Is(err, store.ViolatePK):
+			return c.JSON(http.StatusConflict, err.Error())
+		case errors.Is(err, store.BreedRejected):
+			return c.JSON(http.StatusConflict, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // List Missions
 //
 //	@Summary		List of missions
-//	@Description	List of missions
+//	@Description	List of missions. Without limit or cursor every mission is returned, passing cursor returns a page envelope with next_cursor, prev_cursor and total.
+//	@Description	Missions come with their cat unless include says otherwise, include=targets,cat adds the targets of every mission.
 //	@Tags			mission
-//	@Success		200	{object}	[]store.MissionWithMetadata
-//	@Failure		422	{object}	error
-//	@Failure		400	{object}	error
-//	@Failure		500	{object}	error
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			cursor		query		string	false	"Opaque cursor, switches to keyset pagination (empty for the first page)"
+//	@Param			with_total	query		bool	false	"Include the total number of matching missions"
+//	@Param			status		query		string	false	"Lifecycle state"	Enums(draft, assigned, in_progress, completed, aborted, failed)
+//	@Param			completed	query		bool	false	"Finished or not"
+//	@Param			assigned	query		bool	false	"With or without a cat"
+//	@Param			cat_id		query		int		false	"Cat ID"
+//	@Param			country		query		string	false	"Country of any target (case-insensitive)"
+//	@Param			overdue		query		bool	false	"Marked overdue or not"
+//	@Param			include		query		string	false	"Comma separated expansions: targets, cat"
+//	@Success		200			{object}	[]store.MissionWithMetadata
+//	@Failure		422			{object}	error
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/mission [get]
 //	@Router			/mission/mission_list [get]
 func (app *application) getMissions(c echo.Context) error {
-	list, err := app.store.Mission.GetMissionList(c.Request().Context())
+	filterQuery, err := store.MissionListQuery{}.Parse(c.Request())
 	if err != nil {
-		switch err {
-		case store.ErrNotFound:
-			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if filterQuery.CursorMode && filterQuery.Limit == 0 {
+		filterQuery.Limit = 10
+	}
+	if filterQuery.Limit > 0 {
+		err = Validate.Struct(filterQuery)
+	} else if err = Validate.StructExcept(filterQuery, "PaginatedQuery"); err == nil {
+		err = Validate.Var(filterQuery.Offset, "gte=0")
+	}
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	list, err := app.store.Mission.GetMissionList(c.Request().Context(), filterQuery)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			return c.JSON(http.StatusBadRequest, err.Error())
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
 	}
-	return c.JSON(http.StatusOK, list)
+	return writePage(c, filterQuery.PaginatedQuery, list)
 }
 
 // Get one mission
 //
 //	@Summary		Get one of mission
-//	@Description	Get one of mission by ID
+//	@Description	Get one of mission by ID with its cat and all of its targets. Targets is an empty list for a mission without targets.
 //	@Tags			mission
-//	@Success		200	{object}	nil
+//	@Produce		json
+//	@Success		200	{object}	store.MissionDetail
 //	@Param			id	path		int	true	"Mission ID"
 //	@Failure		422	{object}	error
 //	@Failure		400	{object}	error
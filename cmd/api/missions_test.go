@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindSchedulePatch(t *testing.T) {
+	day := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		body        string
+		startsAt    *time.Time
+		setStartsAt bool
+		deadline    *time.Time
+		setDeadline bool
+		err         bool
+	}{
+		{body: `{}`},
+		{body: `{"starts_at":"2030-01-02T00:00:00Z"}`, startsAt: &day, setStartsAt: true},
+		{body: `{"deadline":null}`, setDeadline: true},
+		{body: `{"starts_at":null,"deadline":"2030-01-02T00:00:00Z"}`, setStartsAt: true, deadline: &day, setDeadline: true},
+		{body: `{"deadline":"tomorrow"}`, err: true},
+		{body: `[]`, err: true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PATCH", "/", strings.NewReader(tt.body))
+		patch, err := bindSchedulePatch(req)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error = %v, want error %v", tt.body, err, tt.err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if patch.SetStartsAt != tt.setStartsAt || patch.SetDeadline != tt.setDeadline {
+			t.Errorf("%s: set starts_at %v, deadline %v, want %v, %v", tt.body, patch.SetStartsAt, patch.SetDeadline, tt.setStartsAt, tt.setDeadline)
+		}
+		if !sameTime(patch.StartsAt, tt.startsAt) || !sameTime(patch.Deadline, tt.deadline) {
+			t.Errorf("%s: got starts_at %v, deadline %v", tt.body, patch.StartsAt, patch.Deadline)
+		}
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// writePage sends one page of a list. Cursor requests get the store.Page
+// envelope, offset requests keep the plain JSON array existing clients expect.
+// Both get RFC 8288 Link headers pointing at the neighbouring pages.
+func writePage[T any](c echo.Context, query store.PaginatedQuery, page *store.Page[T]) error {
+	links := pageLinks(c.Request().URL, query, page)
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+	if query.CursorMode {
+		return c.JSON(http.StatusOK, page)
+	}
+	if page.Total != nil {
+		c.Response().Header().Set("X-Total-Count", strconv.FormatInt(*page.Total, 10))
+	}
+	return c.JSON(http.StatusOK, page.Data)
+}
+
+func pageLinks[T any](u *url.URL, query store.PaginatedQuery, page *store.Page[T]) []string {
+	link := func(rel string, set func(url.Values)) string {
+		values := u.Query()
+		set(values)
+		ref := url.URL{Path: u.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf(`<%s>; rel="%s"`, ref.String(), rel)
+	}
+
+	var links []string
+	if query.CursorMode {
+		if page.NextCursor != "" {
+			links = append(links, link("next", func(v url.Values) { v.Set("cursor", page.NextCursor) }))
+		}
+		if page.PrevCursor != "" {
+			links = append(links, link("prev", func(v url.Values) { v.Set("cursor", page.PrevCursor) }))
+		}
+		return links
+	}
+
+	if query.Limit == 0 {
+		return nil
+	}
+	if page.HasMore {
+		links = append(links, link("next", func(v url.Values) {
+			v.Set("offset", strconv.Itoa(query.Offset+query.Limit))
+		}))
+	}
+	if query.Offset > 0 {
+		links = append(links, link("prev", func(v url.Values) {
+			v.Set("offset", strconv.Itoa(max(query.Offset-query.Limit, 0)))
+		}))
+	}
+	return links
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ScheduleSalaryPayload struct {
+	Salary        int       `json:"salary" validate:"required,gte=1"`
+	EffectiveFrom time.Time `json:"effective_from" validate:"required"`
+	Reason        string    `json:"reason" validate:"max=255"`
+}
+
+// Get salary history godoc
+//
+//	@Summary		Get cat salary history
+//	@Description	Get the salary ledger of a cat, including scheduled raises. With at only the salary in effect at that time is returned.
+//	@Tags			spycat
+//	@Produce		json
+//	@Param			id	path		int		true	"Cat ID"
+//	@Param			at	query		string	false	"RFC 3339 timestamp"
+//	@Success		200	{object}	[]store.SalaryChange
+//	@Failure		400	{object}	error
+//	@Failure		422	{object}	error
+//	@Failure		500	{object}	error
+//	@Router			/spycat/{id}/salary-history [get]
+func (app *application) getSalaryHistoryHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	var at *time.Time
+	if raw := c.QueryParam("at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ValidationError.Error())
+		}
+		at = &parsed
+	}
+
+	history, err := app.store.Salary.GetSalaryHistory(c.Request().Context(), id, at)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, history)
+}
+
+// Schedule salary change godoc
+//
+//	@Summary		Schedule cat salary change
+//	@Description	Schedule a future-dated salary change. It takes effect automatically once effective_from has passed.
+//	@Tags			spycat
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int						true	"Cat ID"
+//	@Param			payload	body		ScheduleSalaryPayload	true	"Salary change payload"
+//	@Success		201		{object}	store.SalaryChange
+//	@Failure		400		{object}	error
+//	@Failure		422		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/spycat/{id}/salary-history [post]
+func (app *application) scheduleSalaryChangeHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+
+	var payload ScheduleSalaryPayload
+	if err = c.Bind(&payload); err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if err = Validate.Struct(payload); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+	if !payload.EffectiveFrom.After(time.Now()) {
+		return c.JSON(http.StatusUnprocessableEntity, "effective_from must be in the future")
+	}
+
+	change := &store.SalaryChange{
+		CatID:         id,
+		Salary:        payload.Salary,
+		Reason:        payload.Reason,
+		EffectiveFrom: payload.EffectiveFrom,
+	}
+	err = app.store.Salary.ScheduleSalaryChange(c.Request().Context(), change)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusCreated, change)
+}
+
+// applyScheduledSalaries is the background job that makes scheduled salary
+// changes take effect.
+func (app *application) applyScheduledSalaries(ctx context.Context) error {
+	applied, err := app.store.Salary.ApplyDueSalaryChanges(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		app.logger.Infow("applied scheduled salary changes", "count", len(applied))
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"FIDOtestBackendApp/internal/store"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type Target struct {
@@ -12,6 +14,10 @@ type Target struct {
 	Country  string `json:"country" validate:"required,max=200,min=1"`
 	Notes    string `json:"notes" validate:"required,max=255,min=1"`
 	Complete *bool  `json:"complete" validate:"required"`
+	// StartsAt and Deadline are optional, the window has to fit into the
+	// mission's.
+	StartsAt *time.Time `json:"starts_at"`
+	Deadline *time.Time `json:"deadline"`
 }
 
 type UpdateNotesPayload struct {
@@ -64,13 +70,14 @@ func (app *application) updateTargetNote(c echo.Context) error {
 // Update target's status
 //
 //	@Summary		Update target's status
-//	@Description	Update target's status  by ID
+//	@Description	Update target's status  by ID. Completing the last target completes the mission when its lifecycle allows it, targets of finished missions cannot change.
 //	@Tags			target
 //	@Produce		json
 //	@Param			mission_id	path		int	true	"mission_id's ID"
 //	@Param			target_id	path		int	true	"target_id's ID"
 //	@Success		200			{object}	store.UpdateTargetStatus
 //	@Failure		422			{object}	error
+//	@Failure		409			{object}	error
 //	@Failure		400			{object}	error
 //	@Failure		500			{object}	error
 //	@Router			/mission/{mission_id}/target_status/{target_id} [patch]
@@ -89,6 +96,8 @@ func (app *application) updateTargetStatus(c echo.Context) error {
 		switch err {
 		case store.ErrNotFound:
 			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case store.MissionCompleted:
+			return c.JSON(http.StatusConflict, err.Error())
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
@@ -129,7 +138,7 @@ func (app *application) deleteTarget(c echo.Context) error {
 // Add target
 //
 //	@Summary		Add target to mission
-//	@Description	Add target to mission by mission_id and target_id
+//	@Description	Add target to mission by mission_id and target_id. An optional starts_at and deadline have to fit into the mission's window.
 //	@Tags			target
 //	@Produce		json
 //	@Param			mission_id	path		int		true	"mission_id's ID"
@@ -157,15 +166,19 @@ func (app *application) addTarget(c echo.Context) error {
 		Name:      payload.Name,
 		Country:   payload.Country,
 		Notes:     payload.Notes,
+		Schedule:  store.Schedule{StartsAt: payload.StartsAt, Deadline: payload.Deadline},
+	}
+	if err = futureDeadline(target.Schedule); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, err.Error())
 	}
 	err = app.store.Target.AddTarget(c.Request().Context(), target)
 	if err != nil {
-		switch err {
-		case store.ErrNotFound:
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrInvalidSchedule):
 			return c.JSON(http.StatusUnprocessableEntity, err.Error())
-		case store.TargetAmountError:
+		case errors.Is(err, store.TargetAmountError):
 			return c.JSON(http.StatusConflict, err.Error())
-		case store.ViolatePK:
+		case errors.Is(err, store.ViolatePK):
 			return c.JSON(http.StatusConflict, err.Error())
 		default:
 			return c.JSON(http.StatusInternalServerError, err.Error())
@@ -174,6 +187,149 @@ func (app *application) addTarget(c echo.Context) error {
 	return c.NoContent(http.StatusCreated)
 }
 
+// Update target schedule
+//
+//	@Summary		Update target schedule
+//	@Description	Change the starts_at and deadline of a target. Members left out keep their value, null clears one. The window has to fit into the mission's and a changed deadline has to be in the future.
+//	@Tags			target
+//	@Accept			json
+//	@Produce		json
+//	@Param			mission_id	path		int				true	"mission_id's ID"
+//	@Param			target_id	path		int				true	"target_id's ID"
+//	@Param			payload		body		SchedulePayload	true	"Target schedule"
+//	@Success		200			{object}	store.Target
+//	@Failure		422			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/mission/{mission_id}/target/{target_id}/schedule [patch]
+func (app *application) updateTargetSchedule(c echo.Context) error {
+	parsedTargetId, parsedMissionId, err := parseParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	patch, err := bindSchedulePatch(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	target, err := app.store.Target.UpdateTargetSchedule(c.Request().Context(), parsedMissionId, parsedTargetId, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrInvalidSchedule):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, store.MissionCompleted):
+			return c.JSON(http.StatusConflict, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, target)
+}
+
+// Get target
+//
+//	@Summary		Get target
+//	@Description	Get one target of a mission
+//	@Tags			target
+//	@Produce		json
+//	@Param			mission_id	path		int	true	"mission_id's ID"
+//	@Param			target_id	path		int	true	"target_id's ID"
+//	@Success		200			{object}	store.Target
+//	@Failure		422			{object}	error
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/mission/{mission_id}/target/{target_id} [get]
+func (app *application) getTarget(c echo.Context) error {
+	parsedTargetId, parsedMissionId, err := parseParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	target, err := app.store.Target.GetTarget(c.Request().Context(), parsedMissionId, parsedTargetId)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, target)
+}
+
+// Get mission targets
+//
+//	@Summary		Get mission targets
+//	@Description	Get every target of a mission in id order
+//	@Tags			target
+//	@Produce		json
+//	@Param			mission_id	path		int	true	"mission_id's ID"
+//	@Success		200			{object}	[]store.Target
+//	@Failure		422			{object}	error
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/mission/{mission_id}/targets [get]
+func (app *application) getMissionTargets(c echo.Context) error {
+	missionID, err := strconv.ParseInt(c.Param("mission_id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	targets, err := app.store.Target.GetMissionTargets(c.Request().Context(), missionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.JSON(http.StatusUnprocessableEntity, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return c.JSON(http.StatusOK, targets)
+}
+
+// List targets
+//
+//	@Summary		List of targets
+//	@Description	Targets of all missions. Passing cursor returns a page envelope with next_cursor, prev_cursor and total.
+//	@Tags			target
+//	@Produce		json
+//	@Param			limit		query		int		false	"Limit"
+//	@Param			offset		query		int		false	"Offset"
+//	@Param			country		query		string	false	"Country (case-insensitive)"
+//	@Param			completed	query		bool	false	"Completed or not"
+//	@Param			search		query		string	false	"Case-insensitive name search"
+//	@Param			cursor		query		string	false	"Opaque cursor, switches to keyset pagination (empty for the first page)"
+//	@Param			with_total	query		bool	false	"Include the total number of matching targets"
+//	@Success		200			{object}	[]store.Target
+//	@Failure		422			{object}	error
+//	@Failure		400			{object}	error
+//	@Failure		500			{object}	error
+//	@Router			/targets [get]
+func (app *application) getTargetListHandler(c echo.Context) error {
+	filterDefault := store.TargetListQuery{
+		PaginatedQuery: store.PaginatedQuery{
+			Limit:  10,
+			Offset: 0,
+		},
+	}
+	filterQuery, err := filterDefault.Parse(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ValidationError.Error())
+	}
+	if err = Validate.Struct(filterQuery); err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, ValidationError.Error())
+	}
+
+	targets, err := app.store.Target.GetTargetList(c.Request().Context(), filterQuery)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidCursor):
+			return c.JSON(http.StatusBadRequest, err.Error())
+		default:
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return writePage(c, filterQuery.PaginatedQuery, targets)
+}
+
 func parseParams(c echo.Context) (targetId, missionId int64, err error) {
 	parsedMissionId, err := strconv.ParseInt(c.Param("mission_id"), 10, 64)
 	if err != nil {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"FIDOtestBackendApp/internal/validation"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -9,6 +8,4 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
-	catValidator := validation.NewClient("https://api.thecatapi.com/v1/breeds")
-	validation.RegisterCatValidator(Validate, catValidator)
 }
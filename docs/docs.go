@@ -24,60 +24,79 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
-            "get": {
-                "description": "Health check",
+        "/admin/breeds/sync": {
+            "post": {
+                "description": "Refresh the local breed catalog from the upstream breed API right away instead of waiting for the scheduled sync.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
                 ],
-                "summary": "Health check",
+                "summary": "Sync breed catalog",
                 "responses": {
-                    "204": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.BreedSyncResult"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {}
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {}
                     }
                 }
             }
         },
-        "/mission": {
-            "post": {
-                "description": "Create new Mission",
-                "consumes": [
+        "/admin/cache/stats": {
+            "get": {
+                "description": "Hit and miss counts of the cat and mission cache, e.g. cat_hits and mission_misses",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Record cache counters",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/spycat/{id}": {
+            "delete": {
+                "description": "Permanently delete an archived spy cat by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "mission"
+                    "admin"
                 ],
-                "summary": "Create Mission",
+                "summary": "Purge spy cat",
                 "parameters": [
                     {
-                        "description": "Mission payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.MissionPayload"
-                        }
+                        "type": "integer",
+                        "description": "Cat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/store.MissionWithTargets"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -94,20 +113,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/mission/mission_list": {
+        "/breeds": {
             "get": {
-                "description": "List of missions",
+                "description": "Fetches catalog breeds sorted by name, each with the number of active spy cats of that breed.\nOffset mode returns a plain array, passing cursor returns a page envelope with next_cursor, prev_cursor and total.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "mission"
+                    "breeds"
+                ],
+                "summary": "Fetches the breed directory",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name search",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching breeds",
+                        "name": "with_total",
+                        "in": "query"
+                    }
                 ],
-                "summary": "List of missions",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/store.MissionWithMetadata"
+                                "$ref": "#/definitions/store.BreedWithCats"
                             }
                         }
                     },
@@ -126,29 +180,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/mission/{id}": {
+        "/breeds/{name}": {
             "get": {
-                "description": "Get one of mission by ID",
+                "description": "Get origin, temperament, description and active spy cat count of a breed. The name is matched ignoring case and whitespace.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "mission"
+                    "breeds"
                 ],
-                "summary": "Get one of mission",
+                "summary": "Get breed details",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Mission ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Breed name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {}
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.BreedWithCats"
+                        }
                     },
                     "422": {
                         "description": "Unprocessable Entity",
@@ -159,80 +215,199 @@ const docTemplate = `{
                         "schema": {}
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete mission by ID",
+            }
+        },
+        "/export/{resource}": {
+            "get": {
+                "description": "Stream every row of a resource as CSV or NDJSON, picked from the Accept header (CSV by default).\nCats accept the same filters and sort options as the cat list, missions and targets the same filters as their lists. Responses are gzipped when the client accepts it.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
                 "tags": [
-                    "mission"
+                    "export"
                 ],
-                "summary": "Delete mission",
+                "summary": "Export cats, missions or targets",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "mission ID",
-                        "name": "id",
+                        "enum": [
+                            "cats",
+                            "missions",
+                            "targets"
+                        ],
+                        "type": "string",
+                        "description": "What to export",
+                        "name": "resource",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "name",
+                            "year_of_experience",
+                            "breed",
+                            "salary"
+                        ],
+                        "type": "string",
+                        "description": "Sort field for cats",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order for cats",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Breed (case-insensitive)",
+                        "name": "breed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name search of cats or targets",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum salary",
+                        "name": "min_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum salary",
+                        "name": "max_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum years of experience",
+                        "name": "min_experience",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum years of experience",
+                        "name": "max_experience",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "deleted"
+                        ],
+                        "type": "string",
+                        "description": "Also export archived cats",
+                        "name": "include",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "verified",
+                            "pending",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Breed verification status",
+                        "name": "breed_status",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "assigned",
+                            "in_progress",
+                            "completed",
+                            "aborted",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Mission lifecycle state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Finished missions or completed targets",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Missions with or without a cat",
+                        "name": "assigned",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Missions marked overdue or not",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cat ID of the missions",
+                        "name": "cat_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target country, for missions of any of their targets (case-insensitive)",
+                        "name": "country",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {}
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {}
                     },
-                    "422": {
-                        "description": "Unprocessable Entity",
+                    "406": {
+                        "description": "Not Acceptable",
                         "schema": {}
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {}
                     }
                 }
-            },
-            "patch": {
-                "description": "Update mission by ID",
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Health check",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "mission"
-                ],
-                "summary": "Update mission",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Mission ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "health"
                 ],
+                "summary": "Health check",
                 "responses": {
-                    "200": {
+                    "204": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.UpdatedMission"
+                            "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {}
-                    },
-                    "422": {
-                        "description": "Unprocessable Entity",
-                        "schema": {}
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {}
@@ -240,37 +415,1354 @@ const docTemplate = `{
                 }
             }
         },
-        "/mission/{id}/cat/{cat_id}": {
-            "patch": {
-                "description": "Add Spy Cat to Mission",
+        "/mission": {
+            "get": {
+                "description": "List of missions. Without limit or cursor every mission is returned, passing cursor returns a page envelope with next_cursor, prev_cursor and total.\nMissions come with their cat unless include says otherwise, include=targets,cat adds the targets of every mission.",
                 "tags": [
                     "mission"
                 ],
-                "summary": "Add Spy Cat to Mission",
+                "summary": "List of missions",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Mission ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching missions",
+                        "name": "with_total",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "assigned",
+                            "in_progress",
+                            "completed",
+                            "aborted",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Finished or not",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "With or without a cat",
+                        "name": "assigned",
+                        "in": "query"
                     },
                     {
                         "type": "integer",
                         "description": "Cat ID",
                         "name": "cat_id",
-                        "in": "path",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country of any target (case-insensitive)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Marked overdue or not",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated expansions: targets, cat",
+                        "name": "include",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.MissionWithMetadata"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "post": {
+                "description": "Create new Mission. It starts as draft, complete has to be false. The optional starts_at and deadline of the mission and its targets are checked: deadlines have to be in the future and after starts_at, target windows have to fit into the mission's.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Create Mission",
+                "parameters": [
+                    {
+                        "description": "Mission payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.MissionPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.MissionWithTargets"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/mission_list": {
+            "get": {
+                "description": "List of missions. Without limit or cursor every mission is returned, passing cursor returns a page envelope with next_cursor, prev_cursor and total.\nMissions come with their cat unless include says otherwise, include=targets,cat adds the targets of every mission.",
+                "tags": [
+                    "mission"
+                ],
+                "summary": "List of missions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching missions",
+                        "name": "with_total",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "draft",
+                            "assigned",
+                            "in_progress",
+                            "completed",
+                            "aborted",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Lifecycle state",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Finished or not",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "With or without a cat",
+                        "name": "assigned",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cat ID",
+                        "name": "cat_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country of any target (case-insensitive)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Marked overdue or not",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated expansions: targets, cat",
+                        "name": "include",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.MissionWithMetadata"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}": {
+            "get": {
+                "description": "Get one of mission by ID with its cat and all of its targets. Targets is an empty list for a mission without targets.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Get one of mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.MissionDetail"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete mission by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Delete mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
                         "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {}
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "patch": {
+                "description": "Mark the mission completed. The mission is moved through its lifecycle, draft and finished missions cannot be completed this way.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Update mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.UpdatedMission"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/store.TransitionError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}/cat": {
+            "delete": {
+                "description": "Take the cat off an incomplete mission",
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Unassign Spy Cat from Mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Why the cat was unassigned",
+                        "name": "reason",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}/cat/{cat_id}": {
+            "patch": {
+                "description": "Add Spy Cat to Mission. Cats whose breed was rejected by verification cannot be assigned.",
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Add Spy Cat to Mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Cat ID",
+                        "name": "cat_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}/reassign": {
+            "post": {
+                "description": "Move the cat of this mission to another incomplete mission in one step. The target has to be a different mission. Cats whose breed was rejected by verification cannot be moved to another mission.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Reassign Spy Cat to another Mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID the cat is moved from",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target mission",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ReassignPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}/schedule": {
+            "patch": {
+                "description": "Change the starts_at and deadline of an unfinished mission. Members left out keep their value, null clears one. A changed deadline has to be in the future, an unchanged one may have passed. The deadline has to leave room for the target windows. Moving the deadline clears the overdue mark.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Update mission schedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Mission schedule",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SchedulePayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Mission"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{id}/transition": {
+            "post": {
+                "description": "Move the mission to another lifecycle state: draft -\u003e assigned | aborted, assigned -\u003e in_progress | draft | aborted, in_progress -\u003e completed | failed | aborted. Completed, aborted and failed missions are frozen. Assigned and in_progress need a cat, moving back to draft takes the cat off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mission"
+                ],
+                "summary": "Transition mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Mission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target state",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TransitionPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Mission"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/store.TransitionError"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{mission_id}/target": {
+            "post": {
+                "description": "Add target to mission by mission_id and target_id. An optional starts_at and deadline have to fit into the mission's window.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Add target to mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.Target"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{mission_id}/target/{target_id}": {
+            "get": {
+                "description": "Get one target of a mission",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Get target",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target_id's ID",
+                        "name": "target_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Target"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete target by mission id and target id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Delete target",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target_id's ID",
+                        "name": "target_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update target's note  by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Update target's note",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target_id's ID",
+                        "name": "target_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update Target note",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateNotesPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.UpdateTargetNote"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{mission_id}/target/{target_id}/schedule": {
+            "patch": {
+                "description": "Change the starts_at and deadline of a target. Members left out keep their value, null clears one. The window has to fit into the mission's and a changed deadline has to be in the future.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Update target schedule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target_id's ID",
+                        "name": "target_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target schedule",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SchedulePayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Target"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{mission_id}/target_status/{target_id}": {
+            "patch": {
+                "description": "Update target's status  by ID. Completing the last target completes the mission when its lifecycle allows it, targets of finished missions cannot change.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Update target's status",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "target_id's ID",
+                        "name": "target_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.UpdateTargetStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/mission/{mission_id}/targets": {
+            "get": {
+                "description": "Get every target of a mission in id order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "target"
+                ],
+                "summary": "Get mission targets",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "mission_id's ID",
+                        "name": "mission_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Target"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/ql": {
+            "get": {
+                "description": "List of cats",
+                "tags": [
+                    "spycat"
+                ],
+                "summary": "List of cats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/spycat": {
+            "get": {
+                "description": "Fetches spy cat list. Offset mode returns a plain array, passing cursor returns a page envelope with next_cursor, prev_cursor and total.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "spycat"
+                ],
+                "summary": "Fetches spy cat list",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "name",
+                            "year_of_experience",
+                            "breed",
+                            "salary"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Breed (case-insensitive)",
+                        "name": "breed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name search",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum salary",
+                        "name": "min_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum salary",
+                        "name": "max_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum years of experience",
+                        "name": "min_experience",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum years of experience",
+                        "name": "max_experience",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "deleted"
+                        ],
+                        "type": "string",
+                        "description": "Also list archived cats",
+                        "name": "include",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "verified",
+                            "pending",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Breed verification status",
+                        "name": "breed_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching cats",
+                        "name": "with_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Cat"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    }
+                }
+            },
+            "post": {
+                "description": "Create new spy cat. The breed is matched against the catalog ignoring case and whitespace and stored under its catalog name.\nAn unknown breed is answered with 422 and the closest catalog breeds as suggestions.\nWith breed_check=deferred a breed that cannot be checked right away, because the catalog is empty or unreachable, is stored as pending (202) and verified later by a background job.\nA breed the catalog already knows to be wrong is still answered with 422.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "spycat"
+                ],
+                "summary": "Create spy cat",
+                "parameters": [
+                    {
+                        "description": "SpyCat payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateCatPayload"
+                        }
+                    },
+                    {
+                        "enum": [
+                            "sync",
+                            "deferred"
+                        ],
+                        "type": "string",
+                        "default": "sync",
+                        "description": "Breed check mode",
+                        "name": "breed_check",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.Cat"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/store.Cat"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {}
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/spycat/available": {
+            "get": {
+                "description": "Fetches spy cats that have no incomplete mission and whose breed was not rejected. Accepts the same filters and pagination as the cat list.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "spycat"
+                ],
+                "summary": "Fetches cats free for a mission",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "name",
+                            "year_of_experience",
+                            "breed",
+                            "salary"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Breed (case-insensitive)",
+                        "name": "breed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name search",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum salary",
+                        "name": "min_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum salary",
+                        "name": "max_salary",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Minimum years of experience",
+                        "name": "min_experience",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum years of experience",
+                        "name": "max_experience",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "verified",
+                            "pending"
+                        ],
+                        "type": "string",
+                        "description": "Breed verification status",
+                        "name": "breed_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching cats",
+                        "name": "with_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Cat"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {}
@@ -282,86 +1774,94 @@ const docTemplate = `{
                 }
             }
         },
-        "/mission/{mission_id}/target": {
+        "/spycat/import": {
             "post": {
-                "description": "Add target to mission by mission_id and target_id",
+                "description": "Import spy cats from CSV (header name,year_of_experience,breed,salary) or NDJSON.\nEvery row is validated like POST /spycat and all valid rows are inserted in one transaction.\nIn atomic mode any invalid row aborts the import, in skip mode invalid rows are reported and left out.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "target"
+                    "spycat"
                 ],
-                "summary": "Add target to mission",
+                "summary": "Bulk import spy cats",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "mission_id's ID",
-                        "name": "mission_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Target payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.Target"
-                        }
+                        "enum": [
+                            "atomic",
+                            "skip"
+                        ],
+                        "type": "string",
+                        "default": "atomic",
+                        "description": "Import mode",
+                        "name": "mode",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ImportResult"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {}
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {}
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {}
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
-                        "schema": {}
+                        "schema": {
+                            "$ref": "#/definitions/main.ImportResult"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {}
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {}
                     }
                 }
             }
         },
-        "/mission/{mission_id}/target/{target_id}": {
-            "delete": {
-                "description": "Delete target by mission id and target id",
+        "/spycat/{id}": {
+            "get": {
+                "description": "Get cat info by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "target"
+                    "spycat"
                 ],
-                "summary": "Delete target",
+                "summary": "Get cat info",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "mission_id's ID",
-                        "name": "mission_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "target_id's ID",
-                        "name": "target_id",
+                        "description": "Cat ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {}
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/store.Cat"
+                        }
                     },
                     "422": {
                         "description": "Unprocessable Entity",
@@ -373,51 +1873,50 @@ const docTemplate = `{
                     }
                 }
             },
-            "patch": {
-                "description": "Update target's note  by ID",
+            "delete": {
+                "description": "Archive spy cat by ID. The cat is hidden from lookups and lists but can be restored.\nCats on an incomplete mission are refused with 409 unless force is set, which unassigns them and records the reason.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "target"
+                    "spycat"
                 ],
-                "summary": "Update target's note",
+                "summary": "Delete spy cat",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "mission_id's ID",
-                        "name": "mission_id",
+                        "description": "Cat ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "target_id's ID",
-                        "name": "target_id",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Unassign the cat from its active missions",
+                        "name": "force",
+                        "in": "query"
                     },
                     {
-                        "description": "Update Target note",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.UpdateNotesPayload"
-                        }
+                        "type": "string",
+                        "description": "Why the cat was unassigned, recorded with force",
+                        "name": "reason",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/store.UpdateTargetNote"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {}
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/store.ActiveMissionError"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {}
@@ -427,45 +1926,53 @@ const docTemplate = `{
                         "schema": {}
                     }
                 }
-            }
-        },
-        "/mission/{mission_id}/target_status/{target_id}": {
+            },
             "patch": {
-                "description": "Update target's status  by ID",
+                "description": "Partially update cat profile by ID with an RFC 7396 merge patch. Only the fields sent are changed, null is rejected because every field is required.\nThe breed is matched like on create, an unknown breed gets 422 with suggestions.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "target"
+                    "spycat"
                 ],
-                "summary": "Update target's status",
+                "summary": "Update cat profile",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "mission_id's ID",
-                        "name": "mission_id",
+                        "description": "Cat ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "target_id's ID",
-                        "name": "target_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Update SpyCat payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UpdateCatInfoPayload"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.UpdateTargetStatus"
+                            "$ref": "#/definitions/store.Cat"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {}
                     },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {}
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {}
@@ -473,56 +1980,31 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {}
-                    }
-                }
-            }
-        },
-        "/ql": {
-            "get": {
-                "description": "List of cats",
-                "tags": [
-                    "spycat"
-                ],
-                "summary": "List of cats",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "string"
-                        }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {}
                     }
                 }
             }
         },
-        "/spycat": {
+        "/spycat/{id}/missions": {
             "get": {
-                "description": "Fetches spy cat list",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get the past and current missions of a cat, newest assignment first",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "spycat"
                 ],
-                "summary": "Fetches spy cat list",
+                "summary": "Get cat missions",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Limit",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Offset",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Cat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -531,7 +2013,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/store.Cat"
+                                "$ref": "#/definitions/store.CatAssignment"
                             }
                         }
                     },
@@ -548,33 +2030,30 @@ const docTemplate = `{
                         "schema": {}
                     }
                 }
-            },
+            }
+        },
+        "/spycat/{id}/restore": {
             "post": {
-                "description": "Create new spy cat",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Restore an archived spy cat by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "spycat"
                 ],
-                "summary": "Create spy cat",
+                "summary": "Restore spy cat",
                 "parameters": [
                     {
-                        "description": "SpyCat payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.CreateCatPayload"
-                        }
+                        "type": "integer",
+                        "description": "Cat ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/store.Cat"
                         }
@@ -594,16 +2073,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/spycat/{id}": {
+        "/spycat/{id}/salary-history": {
             "get": {
-                "description": "Get cat info by ID",
+                "description": "Get the salary ledger of a cat, including scheduled raises. With at only the salary in effect at that time is returned.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "spycat"
                 ],
-                "summary": "Get cat info",
+                "summary": "Get cat salary history",
                 "parameters": [
                     {
                         "type": "integer",
@@ -611,15 +2090,28 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 timestamp",
+                        "name": "at",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.Cat"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.SalaryChange"
+                            }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {}
@@ -630,15 +2122,18 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "Delete spy cat by ID",
+            "post": {
+                "description": "Schedule a future-dated salary change. It takes effect automatically once effective_from has passed.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "spycat"
                 ],
-                "summary": "Delete spy cat",
+                "summary": "Schedule cat salary change",
                 "parameters": [
                     {
                         "type": "integer",
@@ -646,11 +2141,27 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Salary change payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ScheduleSalaryPayload"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/store.SalaryChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {}
                     },
                     "422": {
                         "description": "Unprocessable Entity",
@@ -661,39 +2172,70 @@ const docTemplate = `{
                         "schema": {}
                     }
                 }
-            },
-            "patch": {
-                "description": "Update cat salary by ID",
+            }
+        },
+        "/targets": {
+            "get": {
+                "description": "Targets of all missions. Passing cursor returns a page envelope with next_cursor, prev_cursor and total.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "spycat"
+                    "target"
                 ],
-                "summary": "Update cat salary",
+                "summary": "List of targets",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Cat ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Country (case-insensitive)",
+                        "name": "country",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Completed or not",
+                        "name": "completed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive name search",
+                        "name": "search",
+                        "in": "query"
                     },
                     {
-                        "description": "Update SpyCat payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/main.UpdateCatInfoPayload"
-                        }
+                        "type": "string",
+                        "description": "Opaque cursor, switches to keyset pagination (empty for the first page)",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the total number of matching targets",
+                        "name": "with_total",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/store.Cat"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/store.Target"
+                            }
                         }
                     },
                     "400": {
@@ -713,6 +2255,14 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "main.BreedSyncResult": {
+            "type": "object",
+            "properties": {
+                "synced": {
+                    "type": "integer"
+                }
+            }
+        },
         "main.CreateCatPayload": {
             "type": "object",
             "required": [
@@ -740,6 +2290,49 @@ const docTemplate = `{
                 }
             }
         },
+        "main.ImportResult": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.ImportRowError"
+                    }
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.ImportRowError": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
         "main.MissionPayload": {
             "type": "object",
             "required": [
@@ -750,6 +2343,12 @@ const docTemplate = `{
                 "complete": {
                     "type": "boolean"
                 },
+                "deadline": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
                 "targets": {
                     "type": "array",
                     "maxItems": 3,
@@ -760,9 +2359,57 @@ const docTemplate = `{
                 }
             }
         },
+        "main.ReassignPayload": {
+            "type": "object",
+            "required": [
+                "mission_id"
+            ],
+            "properties": {
+                "mission_id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "main.SchedulePayload": {
+            "type": "object",
+            "properties": {
+                "deadline": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ScheduleSalaryPayload": {
+            "type": "object",
+            "required": [
+                "effective_from",
+                "salary"
+            ],
+            "properties": {
+                "effective_from": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "salary": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
         "main.Target": {
             "type": "object",
             "required": [
+                "complete",
                 "country",
                 "name",
                 "notes"
@@ -776,6 +2423,9 @@ const docTemplate = `{
                     "maxLength": 200,
                     "minLength": 1
                 },
+                "deadline": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string",
                     "maxLength": 200,
@@ -785,18 +2435,60 @@ const docTemplate = `{
                     "type": "string",
                     "maxLength": 255,
                     "minLength": 1
+                },
+                "starts_at": {
+                    "description": "StartsAt and Deadline are optional, the window has to fit into the\nmission's.",
+                    "type": "string"
                 }
             }
         },
-        "main.UpdateCatInfoPayload": {
+        "main.TransitionPayload": {
             "type": "object",
             "required": [
-                "salary"
+                "status"
             ],
             "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "draft",
+                        "assigned",
+                        "in_progress",
+                        "completed",
+                        "aborted",
+                        "failed"
+                    ]
+                }
+            }
+        },
+        "main.UpdateCatInfoPayload": {
+            "type": "object",
+            "properties": {
+                "breed": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200,
+                    "minLength": 1
+                },
                 "salary": {
                     "type": "integer",
                     "minimum": 0
+                },
+                "salary_reason": {
+                    "description": "SalaryReason is not part of the cat, it is recorded in the salary\nhistory when the salary changes.",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "year_of_experience": {
+                    "type": "integer",
+                    "minimum": 1
                 }
             }
         },
@@ -813,12 +2505,61 @@ const docTemplate = `{
                 }
             }
         },
+        "store.ActiveMissionError": {
+            "type": "object",
+            "properties": {
+                "cat_id": {
+                    "type": "integer"
+                },
+                "mission_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "store.BreedWithCats": {
+            "type": "object",
+            "properties": {
+                "active_cats": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "external_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "origin": {
+                    "type": "string"
+                },
+                "synced_at": {
+                    "type": "string"
+                },
+                "temperament": {
+                    "type": "string"
+                }
+            }
+        },
         "store.Cat": {
             "type": "object",
             "properties": {
                 "breed": {
                     "type": "string"
                 },
+                "breed_status": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -833,6 +2574,29 @@ const docTemplate = `{
                 }
             }
         },
+        "store.CatAssignment": {
+            "type": "object",
+            "properties": {
+                "assigned_at": {
+                    "type": "string"
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "mission_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "unassigned_at": {
+                    "type": "string"
+                }
+            }
+        },
         "store.Mission": {
             "type": "object",
             "properties": {
@@ -842,8 +2606,39 @@ const docTemplate = `{
                 "completed": {
                     "type": "boolean"
                 },
+                "deadline": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
+                },
+                "overdue_at": {
+                    "description": "OverdueAt is set once an unfinished mission is past its deadline.",
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is the lifecycle state. Completed is kept for older clients, it\nis true in every finished state.",
+                    "type": "string"
+                }
+            }
+        },
+        "store.MissionDetail": {
+            "type": "object",
+            "properties": {
+                "cat": {
+                    "$ref": "#/definitions/store.Cat"
+                },
+                "mission": {
+                    "$ref": "#/definitions/store.Mission"
+                },
+                "targets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.Target"
+                    }
                 }
             }
         },
@@ -855,6 +2650,13 @@ const docTemplate = `{
                 },
                 "mission": {
                     "$ref": "#/definitions/store.Mission"
+                },
+                "targets": {
+                    "description": "Targets is only loaded when asked for.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/store.Target"
+                    }
                 }
             }
         },
@@ -872,6 +2674,35 @@ const docTemplate = `{
                 }
             }
         },
+        "store.SalaryChange": {
+            "type": "object",
+            "properties": {
+                "cat_id": {
+                    "type": "integer"
+                },
+                "effective_from": {
+                    "type": "string"
+                },
+                "effective_to": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "previous_salary": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "salary": {
+                    "type": "integer"
+                },
+                "scheduled": {
+                    "type": "boolean"
+                }
+            }
+        },
         "store.Target": {
             "type": "object",
             "properties": {
@@ -881,6 +2712,9 @@ const docTemplate = `{
                 "country": {
                     "type": "string"
                 },
+                "deadline": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -892,6 +2726,26 @@ const docTemplate = `{
                 },
                 "notes": {
                     "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.TransitionError": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "mission_id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
                 }
             }
         },
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_spycat_deleted_at;
+
+ALTER TABLE spycat
+    DROP COLUMN IF EXISTS deleted_at;
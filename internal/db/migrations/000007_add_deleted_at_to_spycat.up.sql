@@ -0,0 +1,4 @@
+ALTER TABLE spycat
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_spycat_deleted_at ON spycat(deleted_at);
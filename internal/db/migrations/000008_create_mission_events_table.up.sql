@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS mission_events (
+    id bigserial PRIMARY KEY,
+    mission_id INTEGER NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+    cat_id INTEGER REFERENCES spycat(id) ON DELETE SET NULL,
+    kind VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mission_events_mission_id ON mission_events(mission_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS salary_history (
+    id bigserial PRIMARY KEY,
+    cat_id INTEGER NOT NULL REFERENCES spycat(id) ON DELETE CASCADE,
+    salary INT NOT NULL,
+    previous_salary INT,
+    reason TEXT NOT NULL DEFAULT '',
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+    effective_to TIMESTAMPTZ,
+    applied BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_salary_history_cat_id ON salary_history(cat_id, effective_from);
+CREATE INDEX IF NOT EXISTS idx_salary_history_scheduled ON salary_history(effective_from) WHERE applied = FALSE;
+
+INSERT INTO salary_history (cat_id, salary, reason)
+SELECT id, salary, 'initial' FROM spycat;
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS mission_assignments;
+
+DROP INDEX IF EXISTS unique_active_cat_mission;
+
+-- Before this migration a cat could only be on one mission. Keep each cat on
+-- its active mission, or on its latest one, and take it off the others.
+UPDATE missions m SET cat_id = NULL
+WHERE m.cat_id IS NOT NULL
+  AND m.id <> (
+    SELECT o.id FROM missions o
+    WHERE o.cat_id = m.cat_id
+    ORDER BY o.completed, o.id DESC
+    LIMIT 1
+);
+
+CREATE UNIQUE INDEX unique_cat_mission ON missions(cat_id) WHERE cat_id IS NOT NULL;
@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS unique_cat_mission;
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_cat_mission ON missions(cat_id)
+    WHERE cat_id IS NOT NULL AND completed = FALSE;
+
+CREATE TABLE IF NOT EXISTS mission_assignments (
+    id bigserial PRIMARY KEY,
+    mission_id INTEGER NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+    cat_id INTEGER NOT NULL REFERENCES spycat(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    unassigned_at TIMESTAMPTZ,
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_mission_assignments_cat_id ON mission_assignments(cat_id);
+CREATE UNIQUE INDEX IF NOT EXISTS unique_open_mission_assignment ON mission_assignments(mission_id)
+    WHERE unassigned_at IS NULL;
+
+INSERT INTO mission_assignments (mission_id, cat_id)
+SELECT id, cat_id FROM missions WHERE cat_id IS NOT NULL;
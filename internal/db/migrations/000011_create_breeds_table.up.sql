@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS breeds (
+    id bigserial PRIMARY KEY,
+    external_id VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    origin VARCHAR(255) NOT NULL DEFAULT '',
+    temperament TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    synced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
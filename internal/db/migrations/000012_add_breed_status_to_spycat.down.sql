@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_spycat_breed_status_pending;
+
+ALTER TABLE spycat DROP COLUMN IF EXISTS breed_status;
@@ -0,0 +1,5 @@
+ALTER TABLE spycat
+    ADD COLUMN IF NOT EXISTS breed_status VARCHAR(20) NOT NULL DEFAULT 'verified'
+        CHECK (breed_status IN ('verified', 'pending', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS idx_spycat_breed_status_pending ON spycat (id) WHERE breed_status = 'pending';
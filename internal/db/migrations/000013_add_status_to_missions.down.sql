@@ -0,0 +1,9 @@
+ALTER TABLE mission_events
+    DROP COLUMN IF EXISTS to_status,
+    DROP COLUMN IF EXISTS from_status;
+
+ALTER TABLE missions
+    DROP CONSTRAINT IF EXISTS missions_completed_matches_status,
+    DROP CONSTRAINT IF EXISTS missions_status_check;
+
+ALTER TABLE missions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,19 @@
+ALTER TABLE missions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'draft';
+
+UPDATE missions SET status = CASE
+    WHEN completed THEN 'completed'
+    WHEN cat_id IS NOT NULL THEN 'assigned'
+    ELSE 'draft'
+END;
+
+-- completed stays as the flag older clients read; it is true for every
+-- finished state so existing checks on active missions keep working.
+ALTER TABLE missions
+    ADD CONSTRAINT missions_status_check
+        CHECK (status IN ('draft', 'assigned', 'in_progress', 'completed', 'aborted', 'failed')),
+    ADD CONSTRAINT missions_completed_matches_status
+        CHECK (completed = (status IN ('completed', 'aborted', 'failed')));
+
+ALTER TABLE mission_events
+    ADD COLUMN IF NOT EXISTS from_status VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS to_status VARCHAR(20);
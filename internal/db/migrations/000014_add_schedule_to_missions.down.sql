@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_missions_open_deadline;
+
+ALTER TABLE targets
+    DROP CONSTRAINT IF EXISTS targets_deadline_after_start,
+    DROP COLUMN IF EXISTS deadline,
+    DROP COLUMN IF EXISTS starts_at;
+
+ALTER TABLE missions
+    DROP CONSTRAINT IF EXISTS missions_deadline_after_start,
+    DROP COLUMN IF EXISTS overdue_at,
+    DROP COLUMN IF EXISTS deadline,
+    DROP COLUMN IF EXISTS starts_at;
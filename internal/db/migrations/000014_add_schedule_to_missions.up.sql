@@ -0,0 +1,13 @@
+ALTER TABLE missions
+    ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deadline TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS overdue_at TIMESTAMPTZ,
+    ADD CONSTRAINT missions_deadline_after_start CHECK (deadline > starts_at);
+
+ALTER TABLE targets
+    ADD COLUMN IF NOT EXISTS starts_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deadline TIMESTAMPTZ,
+    ADD CONSTRAINT targets_deadline_after_start CHECK (deadline > starts_at);
+
+CREATE INDEX IF NOT EXISTS idx_missions_open_deadline ON missions (deadline)
+    WHERE deadline IS NOT NULL AND overdue_at IS NULL AND completed = false;
@@ -2,6 +2,8 @@ package env
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 func GetString(key, fallback string) string {
@@ -11,3 +13,27 @@ func GetString(key, fallback string) string {
 	}
 	return val
 }
+
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+	return duration
+}
+
+func GetInt(key string, fallback int) int {
+	val, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"time"
+)
+
+// CatAssignment is one entry of a cat's mission history.
+type CatAssignment struct {
+	MissionID    int64      `json:"mission_id"`
+	Completed    bool       `json:"completed"`
+	Current      bool       `json:"current"`
+	AssignedAt   time.Time  `json:"assigned_at"`
+	UnassignedAt *time.Time `json:"unassigned_at"`
+	Reason       string     `json:"reason,omitempty"`
+}
+
+func (s *MissionStore) GetCatMissions(ctx context.Context, catID int64) ([]*CatAssignment, error) {
+	query := `
+	SELECT a.mission_id, m.completed, a.unassigned_at IS NULL AND NOT m.completed, a.assigned_at, a.unassigned_at, a.reason
+	FROM mission_assignments a
+	JOIN missions m ON m.id = a.mission_id
+	WHERE a.cat_id = $1
+	ORDER BY a.assigned_at DESC, a.id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM spycat WHERE id = $1)`, catID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, catID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assignments := []*CatAssignment{}
+	for rows.Next() {
+		a := &CatAssignment{}
+		err = rows.Scan(&a.MissionID, &a.Completed, &a.Current, &a.AssignedAt, &a.UnassignedAt, &a.Reason)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// assignCat puts the cat on the mission and opens a new assignment record.
+// The unique index on active missions turns a cat that is already busy into
+// ViolatePK.
+func assignCat(ctx context.Context, tx *sql.Tx, missionID, catID int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE missions SET cat_id = $1 WHERE id = $2`, catID, missionID)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return ViolatePK
+			}
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO mission_assignments (mission_id, cat_id) VALUES ($1, $2)`, missionID, catID)
+	return err
+}
+
+// unassignCat takes the cat off the mission and closes its assignment record
+// with the given reason.
+func unassignCat(ctx context.Context, tx *sql.Tx, missionID int64, reason string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE missions SET cat_id = NULL WHERE id = $1`, missionID)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE mission_assignments SET unassigned_at = now(), reason = $2 WHERE mission_id = $1 AND unassigned_at IS NULL`
+	_, err = tx.ExecContext(ctx, query, missionID, reason)
+	return err
+}
+
+// checkAssignable returns ErrNotFound for an archived or missing cat and
+// BreedRejected for a cat whose breed failed verification. The cat row stays
+// share locked, so the cat cannot be archived before the assignment commits.
+func checkAssignable(ctx context.Context, tx *sql.Tx, catID int64) error {
+	var breedStatus string
+	err := tx.QueryRowContext(ctx, `SELECT breed_status FROM spycat WHERE id = $1 AND deleted_at IS NULL FOR SHARE`, catID).Scan(&breedStatus)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if breedStatus == BreedStatusRejected {
+		return BreedRejected
+	}
+	return nil
+}
+
+// lockMission locks the mission row for the rest of the transaction and
+// returns its status and current cat.
+func lockMission(ctx context.Context, tx *sql.Tx, missionID int64) (string, *int64, error) {
+	var status string
+	var catID *int64
+	err := tx.QueryRowContext(ctx, `SELECT status, cat_id FROM missions WHERE id = $1 FOR UPDATE`, missionID).Scan(&status, &catID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", nil, ErrNotFound
+		default:
+			return "", nil, err
+		}
+	}
+	return status, catID, nil
+}
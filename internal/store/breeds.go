@@ -0,0 +1,286 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Breed struct {
+	ID          int64     `json:"id"`
+	ExternalID  string    `json:"external_id"`
+	Name        string    `json:"name"`
+	Origin      string    `json:"origin"`
+	Temperament string    `json:"temperament"`
+	Description string    `json:"description"`
+	SyncedAt    time.Time `json:"synced_at"`
+}
+
+// BreedWithCats is a catalog breed with the number of spy cats of it that
+// are not archived.
+type BreedWithCats struct {
+	Breed
+	ActiveCats int64 `json:"active_cats"`
+}
+
+// normalizedBreed folds case and whitespace of a breed name in SQL the same
+// way validation.NormalizeBreed does in Go.
+const normalizedBreed = `lower(btrim(regexp_replace(%s, '\s+', ' ', 'g')))`
+
+// breedWithCatsQuery selects breeds with their active cat count, in the
+// column order scanBreedWithCats expects.
+var breedWithCatsQuery = `
+	SELECT
+		b.id,
+		b.external_id,
+		b.name,
+		b.origin,
+		b.temperament,
+		b.description,
+		b.synced_at,
+		(SELECT COUNT(*) FROM spycat c
+			WHERE c.deleted_at IS NULL AND ` + fmt.Sprintf(normalizedBreed, "c.breed") + ` = ` + fmt.Sprintf(normalizedBreed, "b.name") + `)
+	FROM breeds b`
+
+func scanBreedWithCats(row scanner) (*BreedWithCats, error) {
+	b := &BreedWithCats{}
+	err := row.Scan(
+		&b.ID,
+		&b.ExternalID,
+		&b.Name,
+		&b.Origin,
+		&b.Temperament,
+		&b.Description,
+		&b.SyncedAt,
+		&b.ActiveCats,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type BreedStore struct {
+	db *sql.DB
+}
+
+// UpsertBreeds writes the breeds into the catalog in one transaction. A breed
+// is matched by name first, since providers use different external ids for
+// the same breed, and by external id otherwise. The external id of a matched
+// breed is only taken over while no other breed holds it. Breeds missing
+// from the input are kept so existing cats never end up with an unknown
+// breed.
+func (s *BreedStore) UpsertBreeds(ctx context.Context, breeds []*Breed) (int, error) {
+	const update = `
+		UPDATE breeds SET
+			external_id = CASE
+				WHEN EXISTS (SELECT 1 FROM breeds o WHERE o.external_id = $1 AND o.id <> breeds.id) THEN breeds.external_id
+				ELSE $1
+			END,
+			origin = $3,
+			temperament = $4,
+			description = $5,
+			synced_at = now()
+		WHERE name = $2
+		RETURNING id, synced_at`
+	const insert = `
+		INSERT INTO breeds (external_id, name, origin, temperament, description, synced_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (external_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			origin = EXCLUDED.origin,
+			temperament = EXCLUDED.temperament,
+			description = EXCLUDED.description,
+			synced_at = EXCLUDED.synced_at
+		RETURNING id, synced_at`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updateStmt, err := tx.PrepareContext(ctx, update)
+	if err != nil {
+		return 0, err
+	}
+	defer updateStmt.Close()
+	insertStmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return 0, err
+	}
+	defer insertStmt.Close()
+
+	for _, breed := range breeds {
+		args := []any{breed.ExternalID, breed.Name, breed.Origin, breed.Temperament, breed.Description}
+		err = updateStmt.QueryRowContext(ctx, args...).Scan(&breed.ID, &breed.SyncedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = insertStmt.QueryRowContext(ctx, args...).Scan(&breed.ID, &breed.SyncedAt)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(breeds), nil
+}
+
+// CatBreedExists reports whether the breed is in the local catalog, ignoring
+// case and extra whitespace.
+func (s *BreedStore) CatBreedExists(ctx context.Context, name string) (bool, error) {
+	_, err := s.MatchBreed(ctx, name)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// MatchBreed finds the catalog entry for name, ignoring case and extra
+// whitespace.
+func (s *BreedStore) MatchBreed(ctx context.Context, name string) (*Breed, error) {
+	query := `
+		SELECT id, external_id, name, origin, temperament, description, synced_at
+		FROM breeds
+		WHERE ` + fmt.Sprintf(normalizedBreed, "name") + ` = ` + fmt.Sprintf(normalizedBreed, "$1") + `
+		ORDER BY id
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	breed := &Breed{}
+	err := s.db.QueryRowContext(ctx, query, name).Scan(
+		&breed.ID,
+		&breed.ExternalID,
+		&breed.Name,
+		&breed.Origin,
+		&breed.Temperament,
+		&breed.Description,
+		&breed.SyncedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return breed, nil
+}
+
+// BreedNames lists the names of all catalog breeds.
+func (s *BreedStore) BreedNames(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM breeds ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetBreedList pages through the catalog by name.
+func (s *BreedStore) GetBreedList(ctx context.Context, paginatedQuery BreedListQuery) (*Page[*BreedWithCats], error) {
+	cursor, err := paginatedQuery.cursor()
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && cursor.Sort != "name" {
+		return nil, ErrInvalidCursor
+	}
+
+	var where string
+	var args []any
+	if paginatedQuery.Search != "" {
+		args = append(args, "%"+escapeLike(paginatedQuery.Search)+"%")
+		where = andWhere(where, fmt.Sprintf("b.name ILIKE $%d", len(args)))
+	}
+	countWhere, countArgs := where, args
+	condition, orderBy, args := keyset("b.name", "b.id", "asc", cursor, args)
+	where = andWhere(where, condition)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	page := &Page[*BreedWithCats]{}
+	if paginatedQuery.WithTotal {
+		var total int64
+		err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM breeds b "+countWhere, countArgs...).Scan(&total)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	// One extra row tells whether there is another page.
+	args = append(args, paginatedQuery.Limit+1)
+	query := fmt.Sprintf("%s %s ORDER BY %s LIMIT $%d", breedWithCatsQuery, where, orderBy, len(args))
+	if !paginatedQuery.CursorMode {
+		args = append(args, paginatedQuery.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	breeds := []*BreedWithCats{}
+	for rows.Next() {
+		b, err := scanBreedWithCats(rows)
+		if err != nil {
+			return nil, err
+		}
+		breeds = append(breeds, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paginate(page, breeds, paginatedQuery.Limit, cursor, func(b *BreedWithCats) Cursor {
+		return Cursor{Sort: "name", Value: b.Name, ID: b.ID}
+	})
+	return page, nil
+}
+
+// GetBreed looks a breed up by name, ignoring case and extra whitespace.
+func (s *BreedStore) GetBreed(ctx context.Context, name string) (*BreedWithCats, error) {
+	query := breedWithCatsQuery + `
+		WHERE ` + fmt.Sprintf(normalizedBreed, "b.name") + ` = ` + fmt.Sprintf(normalizedBreed, "$1") + `
+		ORDER BY b.id
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	b, err := scanBreedWithCats(s.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,18 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is the key/value store behind the typed caches. Values are opaque
+// bytes and every key expires after its own TTL.
+type Backend interface {
+	// Get returns the value of key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetMany stores all values with the same TTL, in one round trip where
+	// the backend supports it.
+	SetMany(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"FIDOtestBackendApp/internal/validation"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// breedSchemaVersion is part of every breed key. Bump it when BreedEntry
+// changes shape, old entries are then simply never read again.
+const breedSchemaVersion = 1
+
+// BreedEntry is the cached outcome of checking a breed name.
+type BreedEntry struct {
+	Valid bool `json:"valid"`
+	// Canonical is the catalog name of a valid breed.
+	Canonical string `json:"canonical,omitempty"`
+	// Suggestions are the closest catalog names of an invalid breed.
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+type BreedCacheConfig struct {
+	Prefix     string
+	ValidTTL   time.Duration
+	InvalidTTL time.Duration
+}
+
+type BreedStore struct {
+	backend Backend
+	config  BreedCacheConfig
+}
+
+// key namespaces the breed under the configured prefix and schema version.
+// Names are normalized, so every spelling of a breed shares one entry.
+func (s *BreedStore) key(breed string) string {
+	return fmt.Sprintf("%s:breed:v%d:%s", s.config.Prefix, breedSchemaVersion, validation.NormalizeBreed(breed))
+}
+
+func (s *BreedStore) ttl(entry BreedEntry) time.Duration {
+	if entry.Valid {
+		return s.config.ValidTTL
+	}
+	return s.config.InvalidTTL
+}
+
+// Get returns the cached entry of breed, or nil when there is none.
+func (s *BreedStore) Get(ctx context.Context, breed string) (*BreedEntry, error) {
+	data, found, err := s.backend.Get(ctx, s.key(breed))
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var entry BreedEntry
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *BreedStore) Set(ctx context.Context, breed string, entry BreedEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.backend.Set(ctx, s.key(breed), data, s.ttl(entry))
+}
+
+// Warm stores every name as a valid breed in one round trip. It overwrites
+// negative entries of breeds that have since joined the catalog.
+func (s *BreedStore) Warm(ctx context.Context, names []string) error {
+	values := make(map[string][]byte, len(names))
+	for _, name := range names {
+		data, err := json.Marshal(BreedEntry{Valid: true, Canonical: name})
+		if err != nil {
+			return err
+		}
+		values[s.key(name)] = data
+	}
+	return s.backend.SetMany(ctx, values, s.config.ValidTTL)
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBackend is an in-process LRU cache with per-key TTLs, for running
+// without Redis. When it holds capacity keys, storing a new one evicts the
+// least recently used.
+type MemoryBackend struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type memoryItem struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryBackend(capacity int) *MemoryBackend {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemoryBackend{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (b *MemoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	item := el.Value.(*memoryItem)
+	if b.expired(item) {
+		b.remove(el)
+		return nil, false, nil
+	}
+	b.order.MoveToFront(el)
+	return item.value, true, nil
+}
+
+func (b *MemoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.set(key, value, ttl)
+	return nil
+}
+
+func (b *MemoryBackend) SetMany(_ context.Context, values map[string][]byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, value := range values {
+		b.set(key, value, ttl)
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(_ context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := b.items[key]; ok {
+			b.remove(el)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) set(key string, value []byte, ttl time.Duration) {
+	// A zero TTL keeps the key until it is evicted, as in Redis.
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = b.now().Add(ttl)
+	}
+
+	if el, ok := b.items[key]; ok {
+		item := el.Value.(*memoryItem)
+		item.value, item.expiresAt = value, expiresAt
+		b.order.MoveToFront(el)
+		return
+	}
+
+	b.items[key] = b.order.PushFront(&memoryItem{key: key, value: value, expiresAt: expiresAt})
+	for b.order.Len() > b.capacity {
+		b.remove(b.order.Back())
+	}
+}
+
+func (b *MemoryBackend) expired(item *memoryItem) bool {
+	return !item.expiresAt.IsZero() && !b.now().Before(item.expiresAt)
+}
+
+func (b *MemoryBackend) remove(el *list.Element) {
+	b.order.Remove(el)
+	delete(b.items, el.Value.(*memoryItem).key)
+}
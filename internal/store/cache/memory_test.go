@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	type op struct {
+		action string // set, get, delete or advance
+		key    string
+		ttl    time.Duration
+		hit    bool
+	}
+
+	tests := []struct {
+		name string
+		ops  []op
+	}{
+		{
+			name: "evicts the least recently stored key",
+			ops: []op{
+				{action: "set", key: "a"},
+				{action: "set", key: "b"},
+				{action: "set", key: "c"},
+				{action: "get", key: "a", hit: false},
+				{action: "get", key: "b", hit: true},
+				{action: "get", key: "c", hit: true},
+			},
+		},
+		{
+			name: "a read keeps a key from eviction",
+			ops: []op{
+				{action: "set", key: "a"},
+				{action: "set", key: "b"},
+				{action: "get", key: "a", hit: true},
+				{action: "set", key: "c"},
+				{action: "get", key: "a", hit: true},
+				{action: "get", key: "b", hit: false},
+			},
+		},
+		{
+			name: "overwriting a key does not evict",
+			ops: []op{
+				{action: "set", key: "a"},
+				{action: "set", key: "b"},
+				{action: "set", key: "a"},
+				{action: "get", key: "a", hit: true},
+				{action: "get", key: "b", hit: true},
+			},
+		},
+		{
+			name: "keys expire after their ttl",
+			ops: []op{
+				{action: "set", key: "a", ttl: time.Minute},
+				{action: "set", key: "b"},
+				{action: "advance", ttl: time.Minute},
+				{action: "get", key: "a", hit: false},
+				{action: "get", key: "b", hit: true},
+			},
+		},
+		{
+			name: "deleted keys are gone",
+			ops: []op{
+				{action: "set", key: "a"},
+				{action: "delete", key: "a"},
+				{action: "get", key: "a", hit: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Unix(0, 0)
+			b := NewMemoryBackend(2)
+			b.now = func() time.Time { return now }
+
+			for i, o := range tt.ops {
+				switch o.action {
+				case "set":
+					if err := b.Set(ctx, o.key, []byte(o.key), o.ttl); err != nil {
+						t.Fatalf("op %d: Set: %v", i, err)
+					}
+				case "delete":
+					if err := b.Delete(ctx, o.key); err != nil {
+						t.Fatalf("op %d: Delete: %v", i, err)
+					}
+				case "advance":
+					now = now.Add(o.ttl)
+				case "get":
+					value, hit, err := b.Get(ctx, o.key)
+					if err != nil {
+						t.Fatalf("op %d: Get: %v", i, err)
+					}
+					if hit != o.hit {
+						t.Fatalf("op %d: Get(%q) hit = %v, want %v", i, o.key, hit, o.hit)
+					}
+					if hit && string(value) != o.key {
+						t.Fatalf("op %d: Get(%q) = %q", i, o.key, value)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package cache
+
+import (
+	"FIDOtestBackendApp/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sync/singleflight"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recordSchemaVersion is part of every record key. Bump it when a cached
+// struct changes shape.
+const recordSchemaVersion = 5
+
+// lookupStats counts the cache lookups of one record kind.
+type lookupStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+var recordStats struct {
+	cat     lookupStats
+	mission lookupStats
+}
+
+// RecordStats returns the hit and miss counters of the record cache, e.g.
+// cat_hits and mission_misses.
+func RecordStats() map[string]int64 {
+	return map[string]int64{
+		"cat_hits":       recordStats.cat.hits.Load(),
+		"cat_misses":     recordStats.cat.misses.Load(),
+		"mission_hits":   recordStats.mission.hits.Load(),
+		"mission_misses": recordStats.mission.misses.Load(),
+	}
+}
+
+type RecordCacheConfig struct {
+	Prefix string
+	TTL    time.Duration
+	// OnError is told about cache failures. They never fail a request, reads
+	// fall back to the database and a failed invalidation is left to the TTL.
+	OnError func(error)
+}
+
+// NewCachedStorage wraps the cat and mission lookups of inner with a
+// read-through cache. Every write that can change a cached cat or mission,
+// including target and salary changes, drops the affected entries.
+func NewCachedStorage(inner store.Storage, backend Backend, config RecordCacheConfig) store.Storage {
+	records := &recordCache{backend: backend, config: config, missions: inner.Mission}
+	cached := inner
+	cached.Cat = &cachedCats{CatStorage: inner.Cat, records: records}
+	cached.Mission = &cachedMissions{MissionStorage: inner.Mission, records: records}
+	cached.Target = &cachedTargets{TargetStorage: inner.Target, records: records}
+	cached.Salary = &cachedSalaries{SalaryStorage: inner.Salary, records: records}
+	return cached
+}
+
+type recordCache struct {
+	backend Backend
+	config  RecordCacheConfig
+	group   singleflight.Group
+	// missions finds the missions that embed a cat.
+	missions store.MissionStorage
+	// generations guard against a load that read a row before a write
+	// committed caching it after the write's invalidation. Keys share
+	// buckets, a collision only costs a skipped cache fill.
+	generations [generationBuckets]generation
+}
+
+const generationBuckets = 256
+
+type generation struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+func (r *recordCache) generation(key string) *generation {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &r.generations[h.Sum32()%generationBuckets]
+}
+
+func (r *recordCache) catKey(id int64) string {
+	return fmt.Sprintf("%s:cat:v%d:%d", r.config.Prefix, recordSchemaVersion, id)
+}
+
+func (r *recordCache) missionKey(id int64) string {
+	return fmt.Sprintf("%s:mission:v%d:%d", r.config.Prefix, recordSchemaVersion, id)
+}
+
+func (r *recordCache) fail(err error) {
+	if err != nil && r.config.OnError != nil {
+		r.config.OnError(err)
+	}
+}
+
+// readThrough serves key from the cache, or loads it once for all concurrent
+// callers and caches the result. Callers get their own copy of the value.
+// The load does not depend on the caller that started it, a caller that
+// gives up does not fail the others waiting on the same key.
+func readThrough[T any](ctx context.Context, r *recordCache, stats *lookupStats, key string, load func(context.Context) (*T, error)) (*T, error) {
+	data, found, err := r.backend.Get(ctx, key)
+	r.fail(err)
+	if found {
+		var value T
+		if err = json.Unmarshal(data, &value); err == nil {
+			stats.hits.Add(1)
+			return &value, nil
+		}
+		r.fail(err)
+	}
+	stats.misses.Add(1)
+
+	result := r.group.DoChan(key, func() (any, error) {
+		ctx := context.WithoutCancel(ctx)
+		gen := r.generation(key)
+		gen.mu.Lock()
+		start := gen.n
+		gen.mu.Unlock()
+
+		value, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err == nil {
+			// Skip the fill if the key was invalidated while loading. The lock
+			// makes an invalidation either land before the check or delete
+			// after the Set.
+			gen.mu.Lock()
+			if gen.n == start {
+				err = r.backend.Set(ctx, key, data, r.config.TTL)
+			}
+			gen.mu.Unlock()
+		}
+		r.fail(err)
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		value := *res.Val.(*T)
+		return &value, nil
+	}
+}
+
+// invalidate drops keys from the cache. Loads of this process that are still
+// running are kept from caching what they read, and the keys are deleted once
+// more after any load that read the old rows must have finished, which covers
+// loads running on other instances.
+func (r *recordCache) invalidate(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		gen := r.generation(key)
+		gen.mu.Lock()
+		gen.n++
+		gen.mu.Unlock()
+	}
+	r.fail(r.backend.Delete(ctx, keys...))
+
+	time.AfterFunc(2*store.QueryTimeOut, func() {
+		r.fail(r.backend.Delete(context.Background(), keys...))
+	})
+}
+
+func (r *recordCache) invalidateMissions(ctx context.Context, ids ...int64) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, r.missionKey(id))
+	}
+	r.invalidate(ctx, keys...)
+}
+
+// catKeys returns the key of the cat and of every mission it was ever on,
+// since a cached mission carries a copy of its cat.
+func (r *recordCache) catKeys(ctx context.Context, id int64) []string {
+	keys := []string{r.catKey(id)}
+	assignments, err := r.missions.GetCatMissions(ctx, id)
+	r.fail(err)
+	for _, a := range assignments {
+		keys = append(keys, r.missionKey(a.MissionID))
+	}
+	return keys
+}
+
+func (r *recordCache) invalidateCat(ctx context.Context, id int64) {
+	r.invalidate(ctx, r.catKeys(ctx, id)...)
+}
+
+type cachedCats struct {
+	store.CatStorage
+	records *recordCache
+}
+
+func (s *cachedCats) GetByID(ctx context.Context, id int64) (*store.Cat, error) {
+	return readThrough(ctx, s.records, &recordStats.cat, s.records.catKey(id), func(ctx context.Context) (*store.Cat, error) {
+		return s.CatStorage.GetByID(ctx, id)
+	})
+}
+
+func (s *cachedCats) DeleteSpyCat(ctx context.Context, id int64, opts store.DeleteCatOptions) error {
+	err := s.CatStorage.DeleteSpyCat(ctx, id, opts)
+	if err == nil {
+		s.records.invalidateCat(ctx, id)
+	}
+	return err
+}
+
+func (s *cachedCats) UpdateSpyCat(ctx context.Context, id int64, patch *store.CatPatch) (*store.Cat, error) {
+	cat, err := s.CatStorage.UpdateSpyCat(ctx, id, patch)
+	if err == nil {
+		s.records.invalidateCat(ctx, id)
+	}
+	return cat, err
+}
+
+func (s *cachedCats) RestoreSpyCat(ctx context.Context, id int64) (*store.Cat, error) {
+	cat, err := s.CatStorage.RestoreSpyCat(ctx, id)
+	if err == nil {
+		s.records.invalidateCat(ctx, id)
+	}
+	return cat, err
+}
+
+func (s *cachedCats) PurgeSpyCat(ctx context.Context, id int64) error {
+	// The assignments are gone after the purge, collect the keys first.
+	keys := s.records.catKeys(ctx, id)
+	err := s.CatStorage.PurgeSpyCat(ctx, id)
+	if err == nil {
+		s.records.invalidate(ctx, keys...)
+	}
+	return err
+}
+
+func (s *cachedCats) ResolveBreed(ctx context.Context, id int64, breed, status string) error {
+	err := s.CatStorage.ResolveBreed(ctx, id, breed, status)
+	if err == nil {
+		s.records.invalidateCat(ctx, id)
+	}
+	return err
+}
+
+type cachedMissions struct {
+	store.MissionStorage
+	records *recordCache
+}
+
+func (s *cachedMissions) GetOneMission(ctx context.Context, id int64) (*store.MissionDetail, error) {
+	return readThrough(ctx, s.records, &recordStats.mission, s.records.missionKey(id), func(ctx context.Context) (*store.MissionDetail, error) {
+		return s.MissionStorage.GetOneMission(ctx, id)
+	})
+}
+
+func (s *cachedMissions) DeleteMission(ctx context.Context, id int64) error {
+	err := s.MissionStorage.DeleteMission(ctx, id)
+	if err == nil {
+		s.records.invalidateMissions(ctx, id)
+	}
+	return err
+}
+
+func (s *cachedMissions) UpdateMissionStatus(ctx context.Context, mission *store.UpdatedMission) error {
+	err := s.MissionStorage.UpdateMissionStatus(ctx, mission)
+	if err == nil {
+		s.records.invalidateMissions(ctx, mission.ID)
+	}
+	return err
+}
+
+func (s *cachedMissions) TransitionMission(ctx context.Context, missionID int64, to, reason string) (*store.Mission, error) {
+	mission, err := s.MissionStorage.TransitionMission(ctx, missionID, to, reason)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return mission, err
+}
+
+func (s *cachedMissions) UpdateMissionSchedule(ctx context.Context, missionID int64, patch store.SchedulePatch) (*store.Mission, error) {
+	mission, err := s.MissionStorage.UpdateMissionSchedule(ctx, missionID, patch)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return mission, err
+}
+
+func (s *cachedMissions) MarkOverdueMissions(ctx context.Context) ([]int64, error) {
+	ids, err := s.MissionStorage.MarkOverdueMissions(ctx)
+	if len(ids) > 0 {
+		s.records.invalidateMissions(ctx, ids...)
+	}
+	return ids, err
+}
+
+func (s *cachedMissions) AddCatToMission(ctx context.Context, catID, missionID int64) error {
+	err := s.MissionStorage.AddCatToMission(ctx, catID, missionID)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return err
+}
+
+func (s *cachedMissions) UnassignCat(ctx context.Context, missionID int64, reason string) error {
+	err := s.MissionStorage.UnassignCat(ctx, missionID, reason)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return err
+}
+
+func (s *cachedMissions) ReassignCat(ctx context.Context, fromMissionID, toMissionID int64, reason string) error {
+	err := s.MissionStorage.ReassignCat(ctx, fromMissionID, toMissionID, reason)
+	if err == nil {
+		s.records.invalidateMissions(ctx, fromMissionID, toMissionID)
+	}
+	return err
+}
+
+type cachedTargets struct {
+	store.TargetStorage
+	records *recordCache
+}
+
+func (s *cachedTargets) UpdateTargetNote(ctx context.Context, updateNote *store.UpdateTargetNote) error {
+	err := s.TargetStorage.UpdateTargetNote(ctx, updateNote)
+	if err == nil {
+		s.records.invalidateMissions(ctx, updateNote.MissionID)
+	}
+	return err
+}
+
+func (s *cachedTargets) UpdateTargetStatus(ctx context.Context, updateTargetStatus *store.UpdateTargetStatus) error {
+	err := s.TargetStorage.UpdateTargetStatus(ctx, updateTargetStatus)
+	if err == nil {
+		s.records.invalidateMissions(ctx, updateTargetStatus.MissionID)
+	}
+	return err
+}
+
+func (s *cachedTargets) UpdateTargetSchedule(ctx context.Context, missionID, targetID int64, patch store.SchedulePatch) (*store.Target, error) {
+	target, err := s.TargetStorage.UpdateTargetSchedule(ctx, missionID, targetID, patch)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return target, err
+}
+
+func (s *cachedTargets) DeleteTarget(ctx context.Context, missionID, targetID int64) error {
+	err := s.TargetStorage.DeleteTarget(ctx, missionID, targetID)
+	if err == nil {
+		s.records.invalidateMissions(ctx, missionID)
+	}
+	return err
+}
+
+func (s *cachedTargets) AddTarget(ctx context.Context, target *store.Target) error {
+	err := s.TargetStorage.AddTarget(ctx, target)
+	if err == nil {
+		s.records.invalidateMissions(ctx, target.MissionID)
+	}
+	return err
+}
+
+type cachedSalaries struct {
+	store.SalaryStorage
+	records *recordCache
+}
+
+func (s *cachedSalaries) ApplyDueSalaryChanges(ctx context.Context) ([]*store.SalaryChange, error) {
+	applied, err := s.SalaryStorage.ApplyDueSalaryChanges(ctx)
+	for _, change := range applied {
+		s.records.invalidateCat(ctx, change.CatID)
+	}
+	return applied, err
+}
@@ -1,6 +1,11 @@
 package cache
 
-import "github.com/go-redis/redis/v8"
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
 
 func NewRedisClient(addr, pw string, db int) *redis.Client {
 	return redis.NewClient(&redis.Options{
@@ -9,3 +14,45 @@ func NewRedisClient(addr, pw string, db int) *redis.Client {
 		DB:       db,
 	})
 }
+
+type RedisBackend struct {
+	client *redis.Client
+}
+
+func NewRedisBackend(client *redis.Client) *RedisBackend {
+	return &RedisBackend{client: client}
+}
+
+func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := b.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+func (b *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (b *RedisBackend) SetMany(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipe := b.client.Pipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, value, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (b *RedisBackend) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return b.client.Del(ctx, keys...).Err()
+}
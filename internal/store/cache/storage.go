@@ -2,18 +2,18 @@ package cache
 
 import (
 	"context"
-	"github.com/go-redis/redis/v8"
 )
 
 type Storage struct {
-	Cats interface {
-		Get(context.Context, string) (bool, error)
-		Set(context.Context, string) error
+	Breeds interface {
+		Get(ctx context.Context, breed string) (*BreedEntry, error)
+		Set(ctx context.Context, breed string, entry BreedEntry) error
+		Warm(ctx context.Context, names []string) error
 	}
 }
 
-func NewRedisStorage(cacheRedis *redis.Client) Storage {
+func NewStorage(backend Backend, breeds BreedCacheConfig) Storage {
 	return Storage{
-		Cats: &CatStore{cacheRedis: cacheRedis},
+		Breeds: &BreedStore{backend: backend, config: breeds},
 	}
 }
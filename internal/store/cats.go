@@ -4,14 +4,36 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Breed statuses of a cat. Cats created while the breed could not be checked
+// stay pending until the verification job marks them verified or rejected.
+const (
+	BreedStatusVerified = "verified"
+	BreedStatusPending  = "pending"
+	BreedStatusRejected = "rejected"
 )
 
 type Cat struct {
-	ID         int64  `json:"id"`
-	Name       string `json:"name"`
-	Experience int    `json:"year_of_experience"`
-	Breed      string `json:"breed"`
-	Salary     int    `json:"salary"`
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Experience  int        `json:"year_of_experience"`
+	Breed       string     `json:"breed"`
+	BreedStatus string     `json:"breed_status"`
+	Salary      int        `json:"salary"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+const catColumns = "id, name, years, breed, breed_status, salary, deleted_at"
+
+// fields returns the scan destinations matching catColumns.
+func (cat *Cat) fields() []any {
+	return []any{&cat.ID, &cat.Name, &cat.Experience, &cat.Breed, &cat.BreedStatus, &cat.Salary, &cat.DeletedAt}
 }
 
 type CatStore struct {
@@ -25,89 +47,504 @@ func NewCatStore(db *sql.DB) *CatStore {
 }
 
 func (s *CatStore) CreateSpyCat(ctx context.Context, cat *Cat) error {
-	query := `INSERT INTO spycat (name, years, breed, salary) VALUES ($1, $2, $3, $4) RETURNING id`
+	query := `INSERT INTO spycat (name, years, breed, breed_status, salary) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	if cat.BreedStatus == "" {
+		cat.BreedStatus = BreedStatusVerified
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, cat.Name, cat.Experience, cat.Breed, cat.Salary).Scan(&cat.ID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, cat.Name, cat.Experience, cat.Breed, cat.BreedStatus, cat.Salary).Scan(&cat.ID)
+	if err != nil {
+		return err
+	}
+	err = recordSalaryChange(ctx, tx, cat.ID, nil, cat.Salary, "initial")
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *CatStore) DeleteSpyCat(ctx context.Context, id int64) error {
-	query := `DELETE FROM spycat WHERE ID =  $1;`
+// CreateSpyCats inserts all cats and their initial salary periods in a single
+// statement, so either every cat is stored or none is. The ids are assigned in
+// the order of cats.
+func (s *CatStore) CreateSpyCats(ctx context.Context, cats []*Cat) error {
+	query := `
+	WITH inserted AS (
+		INSERT INTO spycat (name, years, breed, breed_status, salary)
+		SELECT name, years, breed, breed_status, salary
+		FROM unnest($1::text[], $2::int[], $3::text[], $4::text[], $5::int[])
+			WITH ORDINALITY AS c(name, years, breed, breed_status, salary, n)
+		ORDER BY n
+		RETURNING id, salary
+	), history AS (
+		INSERT INTO salary_history (cat_id, salary, reason)
+		SELECT id, salary, 'initial' FROM inserted
+	)
+	SELECT id FROM inserted ORDER BY id`
+
+	names := make([]string, len(cats))
+	years := make([]int64, len(cats))
+	breeds := make([]string, len(cats))
+	statuses := make([]string, len(cats))
+	salaries := make([]int64, len(cats))
+	for i, cat := range cats {
+		if cat.BreedStatus == "" {
+			cat.BreedStatus = BreedStatusVerified
+		}
+		names[i], years[i], breeds[i] = cat.Name, int64(cat.Experience), cat.Breed
+		statuses[i], salaries[i] = cat.BreedStatus, int64(cat.Salary)
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	res, err := s.db.ExecContext(ctx, query, id)
+	rows, err := s.db.QueryContext(ctx, query,
+		pq.Array(names), pq.Array(years), pq.Array(breeds), pq.Array(statuses), pq.Array(salaries))
 	if err != nil {
 		return err
 	}
-	rows, err := res.RowsAffected()
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() {
+		if i == len(cats) {
+			return errors.New("inserted more cats than given")
+		}
+		if err = rows.Scan(&cats[i].ID); err != nil {
+			return err
+		}
+		i++
+	}
+	return rows.Err()
+}
+
+// DeleteCatOptions controls what happens to the incomplete missions of a cat
+// that is being deleted.
+type DeleteCatOptions struct {
+	// Force unassigns the cat from its active missions instead of failing
+	// with an ActiveMissionError.
+	Force  bool
+	Reason string
+}
+
+// DeleteSpyCat archives the cat. The row is kept with deleted_at set, so the
+// cat's history stays intact and it can be restored later.
+func (s *CatStore) DeleteSpyCat(ctx context.Context, id int64, opts DeleteCatOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return ErrNotFound
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT true FROM spycat WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&exists)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
 	}
-	return nil
+
+	missionIDs, err := activeMissionIDs(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if len(missionIDs) > 0 {
+		if !opts.Force {
+			return &ActiveMissionError{CatID: id, MissionIDs: missionIDs}
+		}
+		for _, missionID := range missionIDs {
+			status, _, err := lockMission(ctx, tx, missionID)
+			if err != nil {
+				return err
+			}
+			if err = unassignCat(ctx, tx, missionID, opts.Reason); err != nil {
+				return err
+			}
+			err = recordMissionEvent(ctx, tx, missionID, &id, MissionEventCatUnassigned, opts.Reason)
+			if err != nil {
+				return err
+			}
+			if err = releaseMission(ctx, tx, missionID, &id, status, opts.Reason); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE spycat SET deleted_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// activeMissionIDs locks and returns the incomplete missions of a cat.
+func activeMissionIDs(ctx context.Context, tx *sql.Tx, catID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM missions WHERE cat_id = $1 AND completed = false ORDER BY id FOR UPDATE`, catID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 func (s *CatStore) GetByID(ctx context.Context, id int64) (*Cat, error) {
-	query := `SELECT id, name, years, breed, salary FROM spycat WHERE id = $1`
+	query := `SELECT ` + catColumns + ` FROM spycat WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 	cat := &Cat{}
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&cat.ID, &cat.Name, &cat.Experience, &cat.Breed, &cat.Salary)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(cat.fields()...)
 	if err != nil {
 		return nil, ErrNotFound
 	}
 	return cat, nil
 }
 
-func (s *CatStore) UpdateSpyCat(ctx context.Context, cat *Cat) error {
-	query := `UPDATE spycat SET salary = $1 WHERE ID = $2 RETURNING id`
+func (s *CatStore) RestoreSpyCat(ctx context.Context, id int64) (*Cat, error) {
+	query := `UPDATE spycat SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING ` + catColumns
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	err := s.db.QueryRowContext(ctx, query, cat.Salary, cat.ID).Scan(&cat.ID)
+	cat := &Cat{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(cat.fields()...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return ErrNotFound
+			return nil, ErrNotFound
 		default:
-			return err
+			return nil, err
 		}
 	}
+	return cat, nil
+}
+
+// PurgeSpyCat permanently removes an archived cat. Active cats have to be
+// deleted first.
+func (s *CatStore) PurgeSpyCat(ctx context.Context, id int64) error {
+	query := `DELETE FROM spycat WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-func (s *CatStore) GetPaginatedSpyCatList(ctx context.Context, paginatedQuery PaginatedQuery) ([]*Cat, error) {
-	query := "SELECT id, name, years, breed, salary FROM spycat ORDER BY id LIMIT $1 OFFSET $2;"
+// CatPatch holds the fields of a partial cat update. Nil fields are left
+// untouched. SalaryReason is recorded in the salary history when the salary
+// changes.
+type CatPatch struct {
+	Name         *string
+	Experience   *int
+	Breed        *string
+	Salary       *int
+	SalaryReason string
+}
+
+func (s *CatStore) UpdateSpyCat(ctx context.Context, id int64, patch *CatPatch) (*Cat, error) {
+	var set []string
+	var args []any
+	column := func(name string, value any) {
+		args = append(args, value)
+		set = append(set, fmt.Sprintf("%s = $%d", name, len(args)))
+	}
+
+	if patch.Name != nil {
+		column("name", *patch.Name)
+	}
+	if patch.Experience != nil {
+		column("years", *patch.Experience)
+	}
+	if patch.Breed != nil {
+		// A patched breed has been checked by the caller.
+		column("breed", *patch.Breed)
+		column("breed_status", BreedStatusVerified)
+	}
+	if patch.Salary != nil {
+		column("salary", *patch.Salary)
+	}
+	if len(set) == 0 {
+		return s.GetByID(ctx, id)
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf(
+		`UPDATE spycat SET %s WHERE id = $%d AND deleted_at IS NULL RETURNING `+catColumns,
+		strings.Join(set, ", "), len(args),
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var previousSalary int
+	err = tx.QueryRowContext(ctx, `SELECT salary FROM spycat WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&previousSalary)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	cat := &Cat{}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(cat.fields()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if cat.Salary != previousSalary {
+		err = recordSalaryChange(ctx, tx, id, &previousSalary, cat.Salary, patch.SalaryReason)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+// GetPendingBreedCats returns up to limit cats whose breed still has to be
+// verified, oldest first.
+func (s *CatStore) GetPendingBreedCats(ctx context.Context, limit int) ([]*Cat, error) {
+	query := `SELECT ` + catColumns + ` FROM spycat WHERE breed_status = $1 AND deleted_at IS NULL ORDER BY id LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, BreedStatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cats := []*Cat{}
+	for rows.Next() {
+		cat := &Cat{}
+		if err = rows.Scan(cat.fields()...); err != nil {
+			return nil, err
+		}
+		cats = append(cats, cat)
+	}
+	return cats, rows.Err()
+}
+
+// ResolveBreed settles a pending breed. Verified cats get the canonical
+// breed name; cats that are no longer pending are left alone.
+func (s *CatStore) ResolveBreed(ctx context.Context, id int64, breed, status string) error {
+	query := `UPDATE spycat SET breed = $1, breed_status = $2 WHERE id = $3 AND breed_status = $4`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, breed, status, id, BreedStatusPending)
+	return err
+}
+
+var catSortColumns = map[string]string{
+	"id":                 "id",
+	"name":               "name",
+	"year_of_experience": "years",
+	"breed":              "breed",
+	"salary":             "salary",
+}
+
+func (s *CatStore) GetPaginatedSpyCatList(ctx context.Context, paginatedQuery CatListQuery) (*Page[*Cat], error) {
+	cursor, err := paginatedQuery.cursor()
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && cursor.Sort != paginatedQuery.Sort {
+		return nil, ErrInvalidCursor
+	}
+
+	sortColumn, ok := catSortColumns[paginatedQuery.Sort]
+	if !ok {
+		sortColumn = "id"
+	}
+
+	where, args := catFilter(paginatedQuery)
+	condition, orderBy, args := keyset(sortColumn, "id", paginatedQuery.Order, cursor, args)
+	where = andWhere(where, condition)
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, paginatedQuery.Limit, paginatedQuery.Offset)
+	page := &Page[*Cat]{}
+	if paginatedQuery.WithTotal {
+		countWhere, countArgs := catFilter(paginatedQuery)
+		var total int64
+		err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM spycat "+countWhere, countArgs...).Scan(&total)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	// One extra row tells whether there is another page.
+	args = append(args, paginatedQuery.Limit+1)
+	query := fmt.Sprintf("SELECT %s FROM spycat %s ORDER BY %s LIMIT $%d", catColumns, where, orderBy, len(args))
+	if !paginatedQuery.CursorMode {
+		args = append(args, paginatedQuery.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
-	var cats []*Cat
+	cats := []*Cat{}
 	for rows.Next() {
 		var cat Cat
-		err = rows.Scan(&cat.ID, &cat.Name, &cat.Experience, &cat.Breed, &cat.Salary)
+		err = rows.Scan(cat.fields()...)
 		if err != nil {
 			return nil, err
 		}
 		cats = append(cats, &cat)
 	}
-	return cats, nil
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paginate(page, cats, paginatedQuery.Limit, cursor, func(cat *Cat) Cursor {
+		return Cursor{Sort: paginatedQuery.Sort, Value: cat.sortValue(paginatedQuery.Sort), ID: cat.ID}
+	})
+	return page, nil
+}
+
+// ExportSpyCats streams every cat matching the filters to fn in the requested order.
+func (s *CatStore) ExportSpyCats(ctx context.Context, filter CatListQuery, fn func(*Cat) error) error {
+	sortColumn, ok := catSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = "id"
+	}
+	where, args := catFilter(filter)
+	_, orderBy, args := keyset(sortColumn, "id", filter.Order, nil, args)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM spycat %s ORDER BY %s", catColumns, where, orderBy), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cat := &Cat{}
+		if err = rows.Scan(cat.fields()...); err != nil {
+			return err
+		}
+		if err = fn(cat); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (cat *Cat) sortValue(sort string) string {
+	switch sort {
+	case "name":
+		return cat.Name
+	case "year_of_experience":
+		return strconv.Itoa(cat.Experience)
+	case "breed":
+		return cat.Breed
+	case "salary":
+		return strconv.Itoa(cat.Salary)
+	default:
+		return strconv.FormatInt(cat.ID, 10)
+	}
+}
+
+// catFilter builds the WHERE clause shared by the cat list queries.
+func catFilter(q CatListQuery) (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Include != "deleted" || q.Available {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if q.Available {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM missions m WHERE m.cat_id = spycat.id AND m.completed = false)")
+		conditions = append(conditions, "breed_status <> '"+BreedStatusRejected+"'")
+	}
+	if q.BreedStatus != "" {
+		add("breed_status = $%d", q.BreedStatus)
+	}
+	if q.Breed != "" {
+		add("LOWER(breed) = LOWER($%d)", q.Breed)
+	}
+	if q.Search != "" {
+		add("name ILIKE '%%' || $%d || '%%'", escapeLike(q.Search))
+	}
+	if q.MinSalary > 0 {
+		add("salary >= $%d", q.MinSalary)
+	}
+	if q.MaxSalary > 0 {
+		add("salary <= $%d", q.MaxSalary)
+	}
+	if q.MinExperience > 0 {
+		add("years >= $%d", q.MinExperience)
+	}
+	if q.MaxExperience > 0 {
+		add("years <= $%d", q.MaxExperience)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
 }
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	MissionEventCatAssigned   = "cat_assigned"
+	MissionEventCatUnassigned = "cat_unassigned"
+	MissionEventStatusChanged = "status_changed"
+	MissionEventOverdue       = "overdue"
+)
+
+// recordMissionEvent appends an entry to the mission audit log. It runs on the
+// caller's transaction so the event is only kept if the change itself is.
+func recordMissionEvent(ctx context.Context, tx *sql.Tx, missionID int64, catID *int64, kind, reason string) error {
+	query := `INSERT INTO mission_events (mission_id, cat_id, kind, reason) VALUES ($1, $2, $3, $4)`
+	_, err := tx.ExecContext(ctx, query, missionID, catID, kind, reason)
+	return err
+}
+
+// recordStatusChange logs a lifecycle transition of the mission.
+func recordStatusChange(ctx context.Context, tx *sql.Tx, missionID int64, catID *int64, from, to, reason string) error {
+	query := `INSERT INTO mission_events (mission_id, cat_id, kind, reason, from_status, to_status) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := tx.ExecContext(ctx, query, missionID, catID, MissionEventStatusChanged, reason, from, to)
+	return err
+}
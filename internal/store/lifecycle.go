@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const (
+	MissionStatusDraft      = "draft"
+	MissionStatusAssigned   = "assigned"
+	MissionStatusInProgress = "in_progress"
+	MissionStatusCompleted  = "completed"
+	MissionStatusAborted    = "aborted"
+	MissionStatusFailed     = "failed"
+)
+
+// missionTransitions lists the states each state may move to. Finished
+// states have no entry, they are frozen.
+var missionTransitions = map[string][]string{
+	MissionStatusDraft:      {MissionStatusAssigned, MissionStatusAborted},
+	MissionStatusAssigned:   {MissionStatusInProgress, MissionStatusDraft, MissionStatusAborted},
+	MissionStatusInProgress: {MissionStatusCompleted, MissionStatusFailed, MissionStatusAborted},
+}
+
+// missionFinished reports whether status is a final state. The completed
+// column is kept true for all of them.
+func missionFinished(status string) bool {
+	switch status {
+	case MissionStatusCompleted, MissionStatusAborted, MissionStatusFailed:
+		return true
+	}
+	return false
+}
+
+// TransitionError reports a status change the mission lifecycle does not
+// allow. It matches ErrInvalidTransition with errors.Is.
+type TransitionError struct {
+	MissionID int64  `json:"mission_id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Reason    string `json:"reason"`
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("mission %d cannot move from %s to %s: %s", e.MissionID, e.From, e.To, e.Reason)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// checkTransition returns a TransitionError unless the mission may move from
+// one state to the other with the cat it has.
+func checkTransition(missionID int64, from, to string, catID *int64) error {
+	fail := func(reason string) error {
+		return &TransitionError{MissionID: missionID, From: from, To: to, Reason: reason}
+	}
+	if missionFinished(from) {
+		return fail("mission is " + from)
+	}
+	allowed := false
+	for _, next := range missionTransitions[from] {
+		allowed = allowed || next == to
+	}
+	if !allowed {
+		return fail("transition not allowed")
+	}
+	if catID == nil && (to == MissionStatusAssigned || to == MissionStatusInProgress) {
+		return fail("mission has no cat")
+	}
+	return nil
+}
+
+// setMissionStatus writes the new state and logs the change. It does not
+// check the transition, callers do that or move the mission as a side effect
+// of another change, e.g. back to draft when its cat is taken off.
+func setMissionStatus(ctx context.Context, tx *sql.Tx, missionID int64, catID *int64, from, to, reason string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE missions SET status = $1, completed = $2 WHERE id = $3`, to, missionFinished(to), missionID)
+	if err != nil {
+		return err
+	}
+	return recordStatusChange(ctx, tx, missionID, catID, from, to, reason)
+}
+
+// releaseMission moves a mission that just lost its cat back to draft, a
+// mission without a cat cannot be assigned or in progress.
+func releaseMission(ctx context.Context, tx *sql.Tx, missionID int64, catID *int64, status, reason string) error {
+	if status != MissionStatusAssigned && status != MissionStatusInProgress {
+		return nil
+	}
+	return setMissionStatus(ctx, tx, missionID, catID, status, MissionStatusDraft, reason)
+}
+
+// completionPath returns the steps that take a mission in the given state to
+// completed, for callers that only know about the completed flag. A nil path
+// means the mission is already completed.
+func completionPath(missionID int64, status string, catID *int64) ([]string, error) {
+	switch status {
+	case MissionStatusCompleted:
+		return nil, nil
+	case MissionStatusAssigned:
+		if err := checkTransition(missionID, status, MissionStatusInProgress, catID); err != nil {
+			return nil, err
+		}
+		return []string{MissionStatusInProgress, MissionStatusCompleted}, nil
+	default:
+		if err := checkTransition(missionID, status, MissionStatusCompleted, catID); err != nil {
+			return nil, err
+		}
+		return []string{MissionStatusCompleted}, nil
+	}
+}
+
+// completeMission walks the mission along its completion path.
+func completeMission(ctx context.Context, tx *sql.Tx, missionID int64, status string, catID *int64, reason string) error {
+	path, err := completionPath(missionID, status, catID)
+	if err != nil {
+		return err
+	}
+	for _, next := range path {
+		if err = setMissionStatus(ctx, tx, missionID, catID, status, next, reason); err != nil {
+			return err
+		}
+		status = next
+	}
+	return nil
+}
+
+// TransitionMission moves the mission to the given state if the lifecycle
+// allows it. Moving back to draft takes the cat off the mission.
+func (s *MissionStore) TransitionMission(ctx context.Context, missionID int64, to, reason string) (*Mission, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, catID, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkTransition(missionID, from, to, catID); err != nil {
+		return nil, err
+	}
+
+	if to == MissionStatusDraft && catID != nil {
+		if err = unassignCat(ctx, tx, missionID, reason); err != nil {
+			return nil, err
+		}
+		if err = recordMissionEvent(ctx, tx, missionID, catID, MissionEventCatUnassigned, reason); err != nil {
+			return nil, err
+		}
+	}
+	if err = setMissionStatus(ctx, tx, missionID, catID, from, to, reason); err != nil {
+		return nil, err
+	}
+
+	mission := &Mission{}
+	err = tx.QueryRowContext(ctx, `SELECT `+missionColumns+` FROM missions WHERE id = $1`, missionID).Scan(mission.fields()...)
+	if err != nil {
+		return nil, err
+	}
+	return mission, tx.Commit()
+}
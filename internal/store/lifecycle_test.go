@@ -0,0 +1,70 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	cat := int64(7)
+	tests := []struct {
+		from, to string
+		catID    *int64
+		ok       bool
+	}{
+		{MissionStatusDraft, MissionStatusAssigned, &cat, true},
+		{MissionStatusDraft, MissionStatusAssigned, nil, false},
+		{MissionStatusDraft, MissionStatusAborted, nil, true},
+		{MissionStatusDraft, MissionStatusCompleted, &cat, false},
+		{MissionStatusAssigned, MissionStatusInProgress, &cat, true},
+		{MissionStatusAssigned, MissionStatusDraft, &cat, true},
+		{MissionStatusInProgress, MissionStatusCompleted, &cat, true},
+		{MissionStatusInProgress, MissionStatusFailed, &cat, true},
+		{MissionStatusInProgress, MissionStatusDraft, &cat, false},
+		{MissionStatusCompleted, MissionStatusDraft, &cat, false},
+		{MissionStatusAborted, MissionStatusAssigned, &cat, false},
+	}
+	for _, tt := range tests {
+		err := checkTransition(1, tt.from, tt.to, tt.catID)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s -> %s: unexpected error %v", tt.from, tt.to, err)
+			}
+			continue
+		}
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) || !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("%s -> %s: error = %v, want a TransitionError", tt.from, tt.to, err)
+			continue
+		}
+		if transitionErr.From != tt.from || transitionErr.To != tt.to {
+			t.Errorf("%s -> %s: error reports %s -> %s", tt.from, tt.to, transitionErr.From, transitionErr.To)
+		}
+	}
+}
+
+func TestCompletionPath(t *testing.T) {
+	cat := int64(7)
+	tests := []struct {
+		status string
+		catID  *int64
+		path   []string
+		ok     bool
+	}{
+		{MissionStatusCompleted, &cat, nil, true},
+		{MissionStatusAssigned, &cat, []string{MissionStatusInProgress, MissionStatusCompleted}, true},
+		{MissionStatusInProgress, &cat, []string{MissionStatusCompleted}, true},
+		{MissionStatusDraft, nil, nil, false},
+		{MissionStatusAborted, &cat, nil, false},
+	}
+	for _, tt := range tests {
+		path, err := completionPath(1, tt.status, tt.catID)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error = %v, want ok %v", tt.status, err, tt.ok)
+		}
+		if !reflect.DeepEqual(path, tt.path) {
+			t.Errorf("%s: path = %v, want %v", tt.status, path, tt.path)
+		}
+	}
+}
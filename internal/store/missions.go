@@ -4,13 +4,30 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/lib/pq"
+	"strings"
+	"time"
 )
 
 type Mission struct {
-	ID        int64  `json:"id"`
-	CatID     *int64 `json:"cat_id"`
+	ID    int64  `json:"id"`
+	CatID *int64 `json:"cat_id"`
+	// Status is the lifecycle state. Completed is kept for older clients, it
+	// is true in every finished state.
+	Status    string `json:"status"`
 	Completed bool   `json:"completed"`
+	Schedule
+	// OverdueAt is set once an unfinished mission is past its deadline.
+	OverdueAt *time.Time `json:"overdue_at"`
+}
+
+// missionColumns is the column list of a plain mission read, in the order
+// fields expects.
+const missionColumns = `id, cat_id, status, completed, starts_at, deadline, overdue_at`
+
+func (m *Mission) fields() []any {
+	return []any{&m.ID, &m.CatID, &m.Status, &m.Completed, &m.StartsAt, &m.Deadline, &m.OverdueAt}
 }
 
 type MissionWithTargets struct {
@@ -20,7 +37,17 @@ type MissionWithTargets struct {
 type MissionWithMetadata struct {
 	Mission Mission
 	Cat     *Cat
+	// Targets is only loaded when asked for.
+	Targets []Target `json:",omitempty"`
+}
+
+// MissionDetail is a single mission with its cat and every target.
+type MissionDetail struct {
+	Mission Mission
+	Cat     *Cat
+	Targets []Target
 }
+
 type MissionStore struct {
 	db *sql.DB
 }
@@ -31,16 +58,31 @@ type UpdatedMission struct {
 }
 
 func (s *MissionStore) CreateMission(ctx context.Context, mission *MissionWithTargets) error {
-	const queryAddMission = `INSERT INTO missions (cat_id, completed) VALUES ($1, $2) RETURNING id`
-	const queryAddTargets = `INSERT INTO targets (mission_id, name, country, notes, completed) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	const queryAddMission = `INSERT INTO missions (cat_id, completed, status, starts_at, deadline) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	const queryAddTargets = `INSERT INTO targets (mission_id, name, country, notes, completed, starts_at, deadline) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	schedules := make([]Schedule, 0, len(mission.Targets))
+	for _, target := range mission.Targets {
+		schedules = append(schedules, target.Schedule)
+	}
+	if err := checkSchedules(mission.Mission.Schedule, schedules...); err != nil {
+		return err
+	}
+	// A new mission starts as draft, it can only be completed through its
+	// lifecycle.
+	if mission.Mission.Completed {
+		return fmt.Errorf("%w: a new mission cannot be completed", ErrInvalidTransition)
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
 	tx, _ := s.db.BeginTx(ctx, nil)
 
+	mission.Mission.Status = MissionStatusDraft
 	var missionID int64
-	err := tx.QueryRowContext(ctx, queryAddMission, mission.Mission.CatID, mission.Mission.Completed).Scan(&missionID)
+	m := &mission.Mission
+	err := tx.QueryRowContext(ctx, queryAddMission, m.CatID, m.Completed, m.Status, m.StartsAt, m.Deadline).Scan(&missionID)
 	if err != nil {
 		_ = tx.Rollback()
 		return err
@@ -49,7 +91,7 @@ func (s *MissionStore) CreateMission(ctx context.Context, mission *MissionWithTa
 	for i := range mission.Targets {
 		target := &mission.Targets[i]
 		target.MissionID = missionID
-		err = tx.QueryRowContext(ctx, queryAddTargets, missionID, target.Name, target.Country, target.Notes, target.Completed).Scan(&target.ID)
+		err = tx.QueryRowContext(ctx, queryAddTargets, missionID, target.Name, target.Country, target.Notes, target.Completed, target.StartsAt, target.Deadline).Scan(&target.ID)
 		if err != nil {
 			if pgErr, ok := err.(*pq.Error); ok {
 				if pgErr.Code == "23505" {
@@ -101,142 +143,353 @@ func (s *MissionStore) DeleteMission(ctx context.Context, id int64) error {
 	return nil
 }
 
+// UpdateMissionStatus sets the legacy completed flag. Completing walks the
+// mission through the lifecycle, so only missions that could get there by
+// transitions are completed. Finished missions cannot be reopened.
 func (s *MissionStore) UpdateMissionStatus(ctx context.Context, missionState *UpdatedMission) error {
-	query := `UPDATE missions SET completed = $1 WHERE id = $2`
-
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	res, err := s.db.ExecContext(ctx, query, missionState.Status, missionState.ID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := res.RowsAffected()
+	status, catID, err := lockMission(ctx, tx, missionState.ID)
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return ErrNotFound
+	if missionState.Status {
+		err = completeMission(ctx, tx, missionState.ID, status, catID, "")
+	} else if missionFinished(status) {
+		err = &TransitionError{MissionID: missionState.ID, From: status, To: MissionStatusDraft, Reason: "mission is " + status}
 	}
-	return nil
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// AddCatToMission assigns the cat to an incomplete mission. A cat can only be
+// on one incomplete mission at a time, but keeps its finished missions in the
+// assignment history. A cat that is replaced is logged as unassigned.
 func (s *MissionStore) AddCatToMission(ctx context.Context, catID, missionID int64) error {
-	var completed bool
-	err := s.db.QueryRowContext(ctx, `
-		SELECT completed FROM missions WHERE id = $1
-	`, missionID).Scan(&completed)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
 
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			return ErrNotFound
-		default:
-			return err
-		}
+		return err
 	}
+	defer tx.Rollback()
 
-	if completed {
+	status, currentCatID, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return err
+	}
+	if missionFinished(status) {
 		return MissionCompleted
 	}
 
-	var exists bool
-	err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM spycat WHERE id = $1)`, catID).Scan(&exists)
+	if err = checkAssignable(ctx, tx, catID); err != nil {
+		return err
+	}
+
+	if currentCatID != nil {
+		if *currentCatID == catID {
+			return nil
+		}
+		if err = unassignCat(ctx, tx, missionID, "replaced"); err != nil {
+			return err
+		}
+		if err = recordMissionEvent(ctx, tx, missionID, currentCatID, MissionEventCatUnassigned, "replaced"); err != nil {
+			return err
+		}
+	}
+	if err = assignCat(ctx, tx, missionID, catID); err != nil {
+		return err
+	}
+	if err = recordMissionEvent(ctx, tx, missionID, &catID, MissionEventCatAssigned, ""); err != nil {
+		return err
+	}
+	if status == MissionStatusDraft {
+		if err = setMissionStatus(ctx, tx, missionID, &catID, status, MissionStatusAssigned, ""); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
 
+// UnassignCat takes the cat off an incomplete mission.
+func (s *MissionStore) UnassignCat(ctx context.Context, missionID int64, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	if !exists {
+	defer tx.Rollback()
+
+	status, catID, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return err
+	}
+	if missionFinished(status) {
+		return MissionCompleted
+	}
+	if catID == nil {
 		return ErrNotFound
 	}
 
-	result, err := s.db.ExecContext(ctx, `
-		UPDATE missions
-		SET cat_id = $1
-		WHERE id = $2
-	`, catID, missionID)
+	if err = unassignCat(ctx, tx, missionID, reason); err != nil {
+		return err
+	}
+	if err = recordMissionEvent(ctx, tx, missionID, catID, MissionEventCatUnassigned, reason); err != nil {
+		return err
+	}
+	if err = releaseMission(ctx, tx, missionID, catID, status, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReassignCat moves the cat of one mission to another in a single
+// transaction. Both missions have to be incomplete, the target mission must
+// not have a cat yet and the cat's breed must not have been rejected. Moving
+// a cat to the mission it is on returns SameMission.
+func (s *MissionStore) ReassignCat(ctx context.Context, fromMissionID, toMissionID int64, reason string) error {
+	if fromMissionID == toMissionID {
+		return SameMission
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
 
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		if pgErr, ok := err.(*pq.Error); ok {
-			if pgErr.Code == "23505" {
-				return ViolatePK
-			}
-		}
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	// Lock in id order so concurrent reassignments between the same missions
+	// cannot deadlock.
+	first, second := fromMissionID, toMissionID
+	if first > second {
+		first, second = second, first
+	}
+	firstStatus, firstCat, err := lockMission(ctx, tx, first)
+	if err != nil {
+		return err
+	}
+	secondStatus, secondCat, err := lockMission(ctx, tx, second)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
+	if missionFinished(firstStatus) || missionFinished(secondStatus) {
+		return MissionCompleted
+	}
+
+	catID, targetCat := firstCat, secondCat
+	fromStatus, toStatus := firstStatus, secondStatus
+	if first != fromMissionID {
+		catID, targetCat = secondCat, firstCat
+		fromStatus, toStatus = secondStatus, firstStatus
+	}
+	if catID == nil {
 		return ErrNotFound
 	}
+	if targetCat != nil {
+		return ViolatePK
+	}
+	if err = checkAssignable(ctx, tx, *catID); err != nil {
+		return err
+	}
 
-	return nil
+	if err = unassignCat(ctx, tx, fromMissionID, reason); err != nil {
+		return err
+	}
+	if err = recordMissionEvent(ctx, tx, fromMissionID, catID, MissionEventCatUnassigned, reason); err != nil {
+		return err
+	}
+	if err = releaseMission(ctx, tx, fromMissionID, catID, fromStatus, reason); err != nil {
+		return err
+	}
+	if err = assignCat(ctx, tx, toMissionID, *catID); err != nil {
+		return err
+	}
+	if err = recordMissionEvent(ctx, tx, toMissionID, catID, MissionEventCatAssigned, reason); err != nil {
+		return err
+	}
+	if toStatus == MissionStatusDraft {
+		if err = setMissionStatus(ctx, tx, toMissionID, catID, toStatus, MissionStatusAssigned, reason); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (s *MissionStore) GetMissionList(ctx context.Context) ([]*MissionWithMetadata, error) {
-	rows, err := s.db.QueryContext(ctx, `
+// missionWithMetadataQuery selects missions joined with their cat, in the
+// column order scanMissionWithMetadata expects.
+const missionWithMetadataQuery = `
 	SELECT 
 		m.id,
+		m.status,
 		m.completed,
 		m.cat_id,
+		m.starts_at,
+		m.deadline,
+		m.overdue_at,
 		c.id,
 		c.name,
 		c.years,
 		c.breed,
-		c.salary
+		c.breed_status,
+		c.salary,
+		c.deleted_at
 	FROM missions m
-	LEFT JOIN spycat c ON m.cat_id = c.id
-	ORDER BY m.id ASC`)
+	LEFT JOIN spycat c ON m.cat_id = c.id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMissionWithMetadata(row scanner) (*MissionWithMetadata, error) {
+	m := &MissionWithMetadata{}
+	var catID sql.NullInt64
+	var catName sql.NullString
+	var catYears sql.NullInt64
+	var catBreed sql.NullString
+	var catBreedStatus sql.NullString
+	var catSalary sql.NullInt64
+	var catDeletedAt *time.Time
+
+	err := row.Scan(
+		&m.Mission.ID,
+		&m.Mission.Status,
+		&m.Mission.Completed,
+		&m.Mission.CatID,
+		&m.Mission.StartsAt,
+		&m.Mission.Deadline,
+		&m.Mission.OverdueAt,
+		&catID,
+		&catName,
+		&catYears,
+		&catBreed,
+		&catBreedStatus,
+		&catSalary,
+		&catDeletedAt,
+	)
 	if err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			return nil, ErrNotFound
-		default:
-			return nil, err
+		return nil, err
+	}
+
+	if catID.Valid {
+		m.Cat = &Cat{
+			ID:          catID.Int64,
+			Name:        catName.String,
+			Experience:  int(catYears.Int64),
+			Breed:       catBreed.String,
+			BreedStatus: catBreedStatus.String,
+			Salary:      int(catSalary.Int64),
+			DeletedAt:   catDeletedAt,
 		}
 	}
-	defer rows.Close()
+	return m, nil
+}
+
+// missionFilter builds the WHERE clause of a mission list query. Column names
+// are qualified, the query joins the missions with their cat.
+func missionFilter(q MissionListQuery) (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Status != "" {
+		add("m.status = $%d", q.Status)
+	}
+	if q.Completed != nil {
+		add("m.completed = $%d", *q.Completed)
+	}
+	if q.Assigned != nil {
+		if *q.Assigned {
+			conditions = append(conditions, "m.cat_id IS NOT NULL")
+		} else {
+			conditions = append(conditions, "m.cat_id IS NULL")
+		}
+	}
+	if q.CatID > 0 {
+		add("m.cat_id = $%d", q.CatID)
+	}
+	if q.Overdue != nil {
+		if *q.Overdue {
+			conditions = append(conditions, "m.overdue_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "m.overdue_at IS NULL")
+		}
+	}
+	if q.Country != "" {
+		add("EXISTS (SELECT 1 FROM targets t WHERE t.mission_id = m.id AND LOWER(t.country) = LOWER($%d))", q.Country)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *MissionStore) GetMissionList(ctx context.Context, paginatedQuery MissionListQuery) (*Page[*MissionWithMetadata], error) {
+	cursor, err := paginatedQuery.cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	where, filterArgs := missionFilter(paginatedQuery)
+	condition, orderBy, args := keyset("m.id", "m.id", "asc", cursor, filterArgs)
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	var missions []*MissionWithMetadata
-	for rows.Next() {
-		m := &MissionWithMetadata{}
-		var catID sql.NullInt64
-		var catName sql.NullString
-		var catYears sql.NullInt64
-		var catBreed sql.NullString
-		var catSalary sql.NullInt64
-
-		err = rows.Scan(
-			&m.Mission.ID,
-			&m.Mission.Completed,
-			&m.Mission.CatID,
-			&catID,
-			&catName,
-			&catYears,
-			&catBreed,
-			&catSalary,
-		)
-		if err != nil {
+	page := &Page[*MissionWithMetadata]{}
+	if paginatedQuery.WithTotal {
+		var total int64
+		if err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM missions m `+where, filterArgs...).Scan(&total); err != nil {
 			return nil, err
 		}
+		page.Total = &total
+	}
 
-		if catID.Valid {
-			m.Cat = &Cat{
-				ID:         catID.Int64,
-				Name:       catName.String,
-				Experience: int(catYears.Int64),
-				Breed:      catBreed.String,
-				Salary:     int(catSalary.Int64),
-			}
-		}
+	query := missionWithMetadataQuery + `
+	` + andWhere(where, condition) + `
+	ORDER BY ` + orderBy
+	// A zero limit keeps the unpaginated listing existing clients rely on.
+	if paginatedQuery.Limit > 0 {
+		args = append(args, paginatedQuery.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if !paginatedQuery.CursorMode && paginatedQuery.Offset > 0 {
+		args = append(args, paginatedQuery.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	missions := []*MissionWithMetadata{}
+	for rows.Next() {
+		m, err := scanMissionWithMetadata(rows)
+		if err != nil {
+			return nil, err
+		}
 		missions = append(missions, m)
 	}
 
@@ -244,59 +497,93 @@ func (s *MissionStore) GetMissionList(ctx context.Context) ([]*MissionWithMetada
 		return nil, err
 	}
 
-	return missions, nil
+	if paginatedQuery.Limit == 0 {
+		page.Data = missions
+	} else {
+		paginate(page, missions, paginatedQuery.Limit, cursor, func(m *MissionWithMetadata) Cursor {
+			return Cursor{Sort: "id", ID: m.Mission.ID}
+		})
+	}
+	if err = s.expand(ctx, page.Data, paginatedQuery); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// expand loads the requested expansions of a page of missions. Targets are
+// fetched for the whole page in one query.
+func (s *MissionStore) expand(ctx context.Context, missions []*MissionWithMetadata, q MissionListQuery) error {
+	if !q.Includes("cat") {
+		for _, m := range missions {
+			m.Cat = nil
+		}
+	}
+	if !q.Includes("targets") || len(missions) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(missions))
+	for _, m := range missions {
+		ids = append(ids, m.Mission.ID)
+	}
+	targets, err := missionTargets(ctx, s.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range missions {
+		m.Targets = targets[m.Mission.ID]
+	}
+	return nil
 }
 
-func (s *MissionStore) GetOneMission(ctx context.Context, id int64) (*MissionWithMetadata, error) {
-	row := s.db.QueryRowContext(ctx, `
-		SELECT 
-			m.id,
-			m.completed,
-			m.cat_id,
-			c.id,
-			c.name,
-			c.years,
-			c.breed,
-			c.salary
-		FROM missions m
-		LEFT JOIN spycat c ON m.cat_id = c.id
-		WHERE m.id = $1`, id)
+// ExportMissions streams the missions matching filter to fn in id order.
+func (s *MissionStore) ExportMissions(ctx context.Context, filter MissionListQuery, fn func(*MissionWithMetadata) error) error {
+	where, args := missionFilter(filter)
+	rows, err := s.db.QueryContext(ctx, missionWithMetadataQuery+` `+where+` ORDER BY m.id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m, err := scanMissionWithMetadata(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
 
+// GetOneMission returns the mission with its cat and all of its targets. Both
+// queries run in one read-only snapshot, so the targets match the mission.
+func (s *MissionStore) GetOneMission(ctx context.Context, id int64) (*MissionDetail, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
-	m := &MissionWithMetadata{}
-	var catID sql.NullInt64
-	var catName sql.NullString
-	var catYears sql.NullInt64
-	var catBreed sql.NullString
-	var catSalary sql.NullInt64
 
-	err := row.Scan(
-		&m.Mission.ID,
-		&m.Mission.Completed,
-		&m.Mission.CatID,
-		&catID,
-		&catName,
-		&catYears,
-		&catBreed,
-		&catSalary,
-	)
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	m, err := scanMissionWithMetadata(tx.QueryRowContext(ctx, missionWithMetadataQuery+` WHERE m.id = $1`, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
-
-	if catID.Valid {
-		m.Cat = &Cat{
-			ID:         catID.Int64,
-			Name:       catName.String,
-			Experience: int(catYears.Int64),
-			Breed:      catBreed.String,
-			Salary:     int(catSalary.Int64),
-		}
+	targets, err := missionTargets(ctx, tx, []int64{id})
+	if err != nil {
+		return nil, err
 	}
 
-	return m, nil
+	detail := &MissionDetail{Mission: m.Mission, Cat: m.Cat, Targets: targets[id]}
+	if detail.Targets == nil {
+		detail.Targets = []Target{}
+	}
+	return detail, tx.Commit()
 }
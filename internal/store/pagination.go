@@ -1,13 +1,116 @@
 package store
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 type PaginatedQuery struct {
 	Limit  int `json:"limit" validate:"gte=1,lte=100"`
 	Offset int `json:"offset" validate:"gte=0"`
+	// Cursor switches the query to keyset pagination. An empty cursor
+	// (?cursor=) requests the first page.
+	Cursor     string `json:"cursor" validate:"max=512"`
+	CursorMode bool   `json:"-"`
+	WithTotal  bool   `json:"with_total"`
+}
+
+// Page is the response envelope for cursor paginated lists.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+	HasMore    bool   `json:"-"`
+}
+
+// Cursor is the decoded form of the opaque cursor handed out to clients. It
+// remembers the sort key and id of the row the page starts after.
+type Cursor struct {
+	Sort     string `json:"s"`
+	Value    string `json:"v"`
+	ID       int64  `json:"id"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// keyset returns the condition and ORDER BY clause for a keyset page sorted
+// by column (with idColumn as tie breaker). When walking backwards the order
+// is flipped, so the caller has to reverse the fetched rows.
+func keyset(column, idColumn, order string, cursor *Cursor, args []any) (string, string, []any) {
+	desc := order == "desc"
+	if cursor != nil && cursor.Backward {
+		desc = !desc
+	}
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
+	}
+
+	orderBy := fmt.Sprintf("%s %s", idColumn, direction)
+	if column != idColumn {
+		orderBy = fmt.Sprintf("%s %s, %s", column, direction, orderBy)
+	}
+	if cursor == nil {
+		return "", orderBy, args
+	}
+
+	if column == idColumn {
+		args = append(args, cursor.ID)
+		return fmt.Sprintf("%s %s $%d", idColumn, op, len(args)), orderBy, args
+	}
+	args = append(args, cursor.Value, cursor.ID)
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", column, idColumn, op, len(args)-1, len(args)), orderBy, args
+}
+
+// paginate trims the extra row fetched to detect another page, restores the
+// requested order for backward pages and fills in the page cursors.
+func paginate[T any](page *Page[T], rows []T, limit int, cursor *Cursor, key func(T) Cursor) {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	page.Data = rows
+	page.HasMore = more
+	if len(rows) == 0 {
+		return
+	}
+
+	if more || backward {
+		page.NextCursor = key(rows[len(rows)-1]).Encode()
+	}
+	if (backward && more) || (!backward && cursor != nil) {
+		prev := key(rows[0])
+		prev.Backward = true
+		page.PrevCursor = prev.Encode()
+	}
 }
 
 func (fq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
@@ -31,5 +134,242 @@ func (fq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
 		fq.Offset = o
 	}
 
+	if q.Has("cursor") {
+		fq.CursorMode = true
+		fq.Cursor = q.Get("cursor")
+	}
+
+	if withTotal := q.Get("with_total"); withTotal != "" {
+		b, err := strconv.ParseBool(withTotal)
+		if err != nil {
+			return fq, err
+		}
+		fq.WithTotal = b
+	}
+
+	return fq, nil
+}
+
+func (fq PaginatedQuery) cursor() (*Cursor, error) {
+	if !fq.CursorMode || fq.Cursor == "" {
+		return nil, nil
+	}
+	return DecodeCursor(fq.Cursor)
+}
+
+type CatListQuery struct {
+	PaginatedQuery
+	Sort          string `json:"sort" validate:"oneof=id name year_of_experience breed salary"`
+	Order         string `json:"order" validate:"oneof=asc desc"`
+	Breed         string `json:"breed" validate:"max=200"`
+	Search        string `json:"search" validate:"max=200"`
+	MinSalary     int    `json:"min_salary" validate:"gte=0"`
+	MaxSalary     int    `json:"max_salary" validate:"omitempty,gtefield=MinSalary"`
+	MinExperience int    `json:"min_experience" validate:"gte=0"`
+	MaxExperience int    `json:"max_experience" validate:"omitempty,gtefield=MinExperience"`
+	Include       string `json:"include" validate:"omitempty,oneof=deleted"`
+	BreedStatus   string `json:"breed_status" validate:"omitempty,oneof=verified pending rejected"`
+	// Available limits the list to cats without an incomplete mission.
+	Available bool `json:"-"`
+}
+
+func (fq CatListQuery) Parse(r *http.Request) (CatListQuery, error) {
+	paginated, err := fq.PaginatedQuery.Parse(r)
+	if err != nil {
+		return fq, err
+	}
+	fq.PaginatedQuery = paginated
+
+	q := r.URL.Query()
+
+	if sort := q.Get("sort"); sort != "" {
+		fq.Sort = sort
+	}
+	if order := q.Get("order"); order != "" {
+		fq.Order = order
+	}
+	if breed := q.Get("breed"); breed != "" {
+		fq.Breed = breed
+	}
+	if search := q.Get("search"); search != "" {
+		fq.Search = search
+	}
+	if include := q.Get("include"); include != "" {
+		fq.Include = include
+	}
+	if breedStatus := q.Get("breed_status"); breedStatus != "" {
+		fq.BreedStatus = breedStatus
+	}
+
+	for key, dst := range map[string]*int{
+		"min_salary":     &fq.MinSalary,
+		"max_salary":     &fq.MaxSalary,
+		"min_experience": &fq.MinExperience,
+		"max_experience": &fq.MaxExperience,
+	} {
+		if err = parseInt(q, key, dst); err != nil {
+			return fq, err
+		}
+	}
+
+	return fq, nil
+}
+
+type BreedListQuery struct {
+	PaginatedQuery
+	Search string `json:"search" validate:"max=200"`
+}
+
+func (fq BreedListQuery) Parse(r *http.Request) (BreedListQuery, error) {
+	paginated, err := fq.PaginatedQuery.Parse(r)
+	if err != nil {
+		return fq, err
+	}
+	fq.PaginatedQuery = paginated
+
+	if search := r.URL.Query().Get("search"); search != "" {
+		fq.Search = search
+	}
+	return fq, nil
+}
+
+type MissionListQuery struct {
+	PaginatedQuery
+	Status    string `json:"status" validate:"omitempty,oneof=draft assigned in_progress completed aborted failed"`
+	Completed *bool  `json:"completed"`
+	// Assigned keeps the missions with (true) or without (false) a cat.
+	Assigned *bool `json:"assigned"`
+	// Overdue keeps the missions that were (true) or were not (false)
+	// marked overdue.
+	Overdue *bool  `json:"overdue"`
+	CatID   int64  `json:"cat_id" validate:"gte=0"`
+	Country string `json:"country" validate:"max=100"`
+	// Include lists the expansions to load. Without it missions come with
+	// their cat, as they always have.
+	Include []string `json:"include" validate:"dive,oneof=targets cat"`
+}
+
+func (fq MissionListQuery) Parse(r *http.Request) (MissionListQuery, error) {
+	paginated, err := fq.PaginatedQuery.Parse(r)
+	if err != nil {
+		return fq, err
+	}
+	fq.PaginatedQuery = paginated
+
+	q := r.URL.Query()
+
+	if status := q.Get("status"); status != "" {
+		fq.Status = status
+	}
+	if country := q.Get("country"); country != "" {
+		fq.Country = country
+	}
+	if catID := q.Get("cat_id"); catID != "" {
+		if fq.CatID, err = strconv.ParseInt(catID, 10, 64); err != nil {
+			return fq, err
+		}
+	}
+	if include := q.Get("include"); include != "" {
+		fq.Include = nil
+		for _, part := range strings.Split(include, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				fq.Include = append(fq.Include, part)
+			}
+		}
+	}
+
+	for key, dst := range map[string]**bool{
+		"completed": &fq.Completed,
+		"assigned":  &fq.Assigned,
+		"overdue":   &fq.Overdue,
+	} {
+		if err = parseBool(q, key, dst); err != nil {
+			return fq, err
+		}
+	}
+
 	return fq, nil
 }
+
+// Includes reports whether the expansion was requested.
+func (fq MissionListQuery) Includes(expansion string) bool {
+	if fq.Include == nil {
+		return expansion == "cat"
+	}
+	for _, include := range fq.Include {
+		if include == expansion {
+			return true
+		}
+	}
+	return false
+}
+
+type TargetListQuery struct {
+	PaginatedQuery
+	Country   string `json:"country" validate:"max=200"`
+	Completed *bool  `json:"completed"`
+	Search    string `json:"search" validate:"max=200"`
+}
+
+func (fq TargetListQuery) Parse(r *http.Request) (TargetListQuery, error) {
+	paginated, err := fq.PaginatedQuery.Parse(r)
+	if err != nil {
+		return fq, err
+	}
+	fq.PaginatedQuery = paginated
+
+	q := r.URL.Query()
+
+	if country := q.Get("country"); country != "" {
+		fq.Country = country
+	}
+	if search := q.Get("search"); search != "" {
+		fq.Search = search
+	}
+	if err = parseBool(q, "completed", &fq.Completed); err != nil {
+		return fq, err
+	}
+
+	return fq, nil
+}
+
+func parseInt(q url.Values, key string, dst *int) error {
+	val := q.Get(key)
+	if val == "" {
+		return nil
+	}
+	parsed, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	*dst = parsed
+	return nil
+}
+
+func parseBool(q url.Values, key string, dst **bool) error {
+	val := q.Get(key)
+	if val == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*dst = &parsed
+	return nil
+}
+
+func andWhere(where, condition string) string {
+	switch {
+	case condition == "":
+		return where
+	case where == "":
+		return "WHERE " + condition
+	default:
+		return where + " AND " + condition
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
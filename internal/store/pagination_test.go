@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Sort: "id", ID: 1},
+		{Sort: "name", Value: "Tom", ID: 42},
+		{Sort: "salary", Value: "1000", ID: 7, Backward: true},
+	}
+	for _, want := range tests {
+		got, err := DecodeCursor(want.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v): %v", want, err)
+		}
+		if *got != want {
+			t.Errorf("round trip = %+v, want %+v", *got, want)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	tests := []string{
+		"not base64!",
+		"bm90IGpzb24", // "not json"
+	}
+	for _, input := range tests {
+		if _, err := DecodeCursor(input); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", input, err)
+		}
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		order     string
+		cursor    *Cursor
+		condition string
+		orderBy   string
+		args      []any
+	}{
+		{
+			name:    "first page by id",
+			column:  "id",
+			order:   "asc",
+			orderBy: "id ASC",
+		},
+		{
+			name:      "next page by id",
+			column:    "id",
+			order:     "asc",
+			cursor:    &Cursor{ID: 5},
+			condition: "id > $2",
+			orderBy:   "id ASC",
+			args:      []any{int64(5)},
+		},
+		{
+			name:      "next page by name descending",
+			column:    "name",
+			order:     "desc",
+			cursor:    &Cursor{Value: "Tom", ID: 5},
+			condition: "(name, id) < ($2, $3)",
+			orderBy:   "name DESC, id DESC",
+			args:      []any{"Tom", int64(5)},
+		},
+		{
+			name:      "previous page flips the order",
+			column:    "name",
+			order:     "asc",
+			cursor:    &Cursor{Value: "Tom", ID: 5, Backward: true},
+			condition: "(name, id) < ($2, $3)",
+			orderBy:   "name DESC, id DESC",
+			args:      []any{"Tom", int64(5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, orderBy, args := keyset(tt.column, "id", tt.order, tt.cursor, []any{"filter"})
+			if condition != tt.condition {
+				t.Errorf("condition = %q, want %q", condition, tt.condition)
+			}
+			if orderBy != tt.orderBy {
+				t.Errorf("orderBy = %q, want %q", orderBy, tt.orderBy)
+			}
+			if want := append([]any{"filter"}, tt.args...); !reflect.DeepEqual(args, want) {
+				t.Errorf("args = %v, want %v", args, want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	key := func(id int64) Cursor { return Cursor{Sort: "id", ID: id} }
+	cursorID := func(t *testing.T, s string) int64 {
+		t.Helper()
+		c, err := DecodeCursor(s)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", s, err)
+		}
+		return c.ID
+	}
+
+	tests := []struct {
+		name    string
+		rows    []int64
+		cursor  *Cursor
+		data    []int64
+		hasMore bool
+		next    int64
+		prev    int64
+	}{
+		{
+			name:    "first page with more",
+			rows:    []int64{1, 2, 3},
+			data:    []int64{1, 2},
+			hasMore: true,
+			next:    2,
+		},
+		{
+			name: "only page",
+			rows: []int64{1, 2},
+			data: []int64{1, 2},
+		},
+		{
+			name:   "last page after a cursor",
+			rows:   []int64{3, 4},
+			cursor: &Cursor{ID: 2},
+			data:   []int64{3, 4},
+			prev:   3,
+		},
+		{
+			name:    "backward page is reversed",
+			rows:    []int64{4, 3, 2},
+			cursor:  &Cursor{ID: 5, Backward: true},
+			data:    []int64{3, 4},
+			hasMore: true,
+			next:    4,
+			prev:    3,
+		},
+		{
+			name: "empty page",
+			rows: []int64{},
+			data: []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page Page[int64]
+			paginate(&page, tt.rows, 2, tt.cursor, key)
+			if !reflect.DeepEqual(page.Data, tt.data) {
+				t.Errorf("data = %v, want %v", page.Data, tt.data)
+			}
+			if page.HasMore != tt.hasMore {
+				t.Errorf("HasMore = %v, want %v", page.HasMore, tt.hasMore)
+			}
+			switch {
+			case tt.next == 0 && page.NextCursor != "":
+				t.Errorf("unexpected next cursor %q", page.NextCursor)
+			case tt.next != 0 && cursorID(t, page.NextCursor) != tt.next:
+				t.Errorf("next cursor id = %d, want %d", cursorID(t, page.NextCursor), tt.next)
+			}
+			switch {
+			case tt.prev == 0 && page.PrevCursor != "":
+				t.Errorf("unexpected prev cursor %q", page.PrevCursor)
+			case tt.prev != 0 && cursorID(t, page.PrevCursor) != tt.prev:
+				t.Errorf("prev cursor id = %d, want %d", cursorID(t, page.PrevCursor), tt.prev)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SalaryChange is one period of the salary ledger. Scheduled changes are
+// future-dated raises that have not taken effect yet.
+type SalaryChange struct {
+	ID             int64      `json:"id"`
+	CatID          int64      `json:"cat_id"`
+	Salary         int        `json:"salary"`
+	PreviousSalary *int       `json:"previous_salary"`
+	Reason         string     `json:"reason"`
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveTo    *time.Time `json:"effective_to"`
+	Scheduled      bool       `json:"scheduled"`
+}
+
+type SalaryStore struct {
+	db *sql.DB
+}
+
+// GetSalaryHistory returns the salary ledger of a cat. With at set only the
+// period in effect at that moment is returned.
+func (s *SalaryStore) GetSalaryHistory(ctx context.Context, catID int64, at *time.Time) ([]*SalaryChange, error) {
+	query := `
+	SELECT id, cat_id, salary, previous_salary, reason, effective_from, effective_to, NOT applied
+	FROM salary_history
+	WHERE cat_id = $1`
+	args := []any{catID}
+	if at != nil {
+		query += ` AND applied AND effective_from <= $2 AND (effective_to IS NULL OR effective_to > $2)`
+		args = append(args, *at)
+	}
+	query += ` ORDER BY effective_from, id`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM spycat WHERE id = $1)`, catID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*SalaryChange{}
+	for rows.Next() {
+		change := &SalaryChange{}
+		err = rows.Scan(
+			&change.ID,
+			&change.CatID,
+			&change.Salary,
+			&change.PreviousSalary,
+			&change.Reason,
+			&change.EffectiveFrom,
+			&change.EffectiveTo,
+			&change.Scheduled,
+		)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+// ScheduleSalaryChange stores a future-dated salary change. It is applied by
+// ApplyDueSalaryChanges once its effective date has passed.
+func (s *SalaryStore) ScheduleSalaryChange(ctx context.Context, change *SalaryChange) error {
+	query := `
+	INSERT INTO salary_history (cat_id, salary, reason, effective_from, applied)
+	SELECT id, $2, $3, $4, false FROM spycat WHERE id = $1 AND deleted_at IS NULL
+	RETURNING id`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, change.CatID, change.Salary, change.Reason, change.EffectiveFrom).Scan(&change.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+	change.Scheduled = true
+	return nil
+}
+
+// ApplyDueSalaryChanges applies scheduled changes whose effective date has
+// passed and returns the applied changes. Changes of archived cats wait until
+// the cat is restored. A change never starts before the period it replaces,
+// so a manual change made after its date keeps a valid period.
+func (s *SalaryStore) ApplyDueSalaryChanges(ctx context.Context) ([]*SalaryChange, error) {
+	query := `
+	SELECT h.id, h.cat_id, h.salary, h.effective_from
+	FROM salary_history h
+	JOIN spycat c ON c.id = h.cat_id AND c.deleted_at IS NULL
+	WHERE h.applied = false AND h.effective_from <= now()
+	ORDER BY h.effective_from, h.id
+	LIMIT 100
+	FOR UPDATE OF h SKIP LOCKED`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var due []*SalaryChange
+	for rows.Next() {
+		change := &SalaryChange{}
+		if err = rows.Scan(&change.ID, &change.CatID, &change.Salary, &change.EffectiveFrom); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, change)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	applied := make([]*SalaryChange, 0, len(due))
+	for _, change := range due {
+		var previous int
+		err = tx.QueryRowContext(ctx, `SELECT salary FROM spycat WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, change.CatID).Scan(&previous)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Archived since the change was selected.
+				continue
+			}
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE spycat SET salary = $1 WHERE id = $2`, change.Salary, change.CatID)
+		if err != nil {
+			return nil, err
+		}
+		change.EffectiveFrom, err = closeSalaryPeriod(ctx, tx, change.CatID, change.EffectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		query := `UPDATE salary_history SET applied = true, previous_salary = $1, effective_from = $2 WHERE id = $3`
+		_, err = tx.ExecContext(ctx, query, previous, change.EffectiveFrom, change.ID)
+		if err != nil {
+			return nil, err
+		}
+		change.PreviousSalary = &previous
+		applied = append(applied, change)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// recordSalaryChange closes the cat's current salary period and opens a new
+// one starting now. It runs on the transaction that changed the salary.
+func recordSalaryChange(ctx context.Context, tx *sql.Tx, catID int64, previous *int, salary int, reason string) error {
+	var now time.Time
+	if err := tx.QueryRowContext(ctx, `SELECT now()`).Scan(&now); err != nil {
+		return err
+	}
+	now, err := closeSalaryPeriod(ctx, tx, catID, now)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO salary_history (cat_id, salary, previous_salary, reason, effective_from)
+	VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, catID, salary, previous, reason, now)
+	return err
+}
+
+// closeSalaryPeriod ends the cat's open salary period at until, or at its
+// start if that is later, and returns when the next period starts.
+func closeSalaryPeriod(ctx context.Context, tx *sql.Tx, catID int64, until time.Time) (time.Time, error) {
+	query := `
+	UPDATE salary_history SET effective_to = GREATEST(effective_from, $2)
+	WHERE cat_id = $1 AND applied AND effective_to IS NULL
+	RETURNING effective_to`
+
+	var closedAt time.Time
+	err := tx.QueryRowContext(ctx, query, catID, until).Scan(&closedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return until, nil
+		}
+		return time.Time{}, err
+	}
+	return closedAt, nil
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schedule is the optional time window of a mission or a target.
+type Schedule struct {
+	StartsAt *time.Time `json:"starts_at"`
+	Deadline *time.Time `json:"deadline"`
+}
+
+// SchedulePatch changes the time window of a mission or a target. Only the
+// values whose Set flag is true change, a nil value clears them.
+type SchedulePatch struct {
+	StartsAt    *time.Time
+	SetStartsAt bool
+	Deadline    *time.Time
+	SetDeadline bool
+}
+
+func (p SchedulePatch) apply(s Schedule) Schedule {
+	if p.SetStartsAt {
+		s.StartsAt = p.StartsAt
+	}
+	if p.SetDeadline {
+		s.Deadline = p.Deadline
+	}
+	return s
+}
+
+func invalidSchedule(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSchedule, reason)
+}
+
+func (s Schedule) check() error {
+	if s.StartsAt != nil && s.Deadline != nil && !s.Deadline.After(*s.StartsAt) {
+		return invalidSchedule("deadline must be after starts_at")
+	}
+	return nil
+}
+
+// checkDeadlineMove rejects moving a deadline into the past. A deadline that
+// stays as it is may have passed, so an overdue window can still be edited.
+func checkDeadlineMove(current, next *time.Time) error {
+	if next == nil || (current != nil && current.Equal(*next)) {
+		return nil
+	}
+	if !next.After(time.Now()) {
+		return invalidSchedule("deadline must be in the future")
+	}
+	return nil
+}
+
+// checkSchedules checks the window of a mission and that the windows of its
+// targets fit into it.
+func checkSchedules(mission Schedule, targets ...Schedule) error {
+	if err := mission.check(); err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := target.check(); err != nil {
+			return err
+		}
+		if mission.StartsAt != nil && target.StartsAt != nil && target.StartsAt.Before(*mission.StartsAt) {
+			return invalidSchedule("target starts_at is before the mission starts_at")
+		}
+		if mission.Deadline != nil && target.Deadline != nil && target.Deadline.After(*mission.Deadline) {
+			return invalidSchedule("target deadline is after the mission deadline")
+		}
+	}
+	return nil
+}
+
+func missionSchedule(ctx context.Context, tx *sql.Tx, missionID int64) (Schedule, error) {
+	var s Schedule
+	err := tx.QueryRowContext(ctx, `SELECT starts_at, deadline FROM missions WHERE id = $1`, missionID).Scan(&s.StartsAt, &s.Deadline)
+	return s, err
+}
+
+func targetSchedules(ctx context.Context, tx *sql.Tx, missionID int64) ([]Schedule, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT starts_at, deadline FROM targets WHERE mission_id = $1`, missionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var s Schedule
+		if err = rows.Scan(&s.StartsAt, &s.Deadline); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// UpdateMissionSchedule patches the time window of an unfinished mission. A
+// changed deadline has to be in the future. Moving the deadline into the
+// future, or dropping it, clears the overdue mark.
+func (s *MissionStore) UpdateMissionSchedule(ctx context.Context, missionID int64, patch SchedulePatch) (*Mission, error) {
+	query := `
+	UPDATE missions
+	SET starts_at = $2,
+	    deadline = $3,
+	    overdue_at = CASE WHEN $3::timestamptz IS NULL OR $3::timestamptz > now() THEN NULL ELSE overdue_at END
+	WHERE id = $1
+	RETURNING ` + missionColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if missionFinished(status) {
+		return nil, MissionCompleted
+	}
+	current, err := missionSchedule(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	schedule := patch.apply(current)
+	if err = checkDeadlineMove(current.Deadline, schedule.Deadline); err != nil {
+		return nil, err
+	}
+	targets, err := targetSchedules(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSchedules(schedule, targets...); err != nil {
+		return nil, err
+	}
+
+	mission := &Mission{}
+	if err = tx.QueryRowContext(ctx, query, missionID, schedule.StartsAt, schedule.Deadline).Scan(mission.fields()...); err != nil {
+		return nil, err
+	}
+	return mission, tx.Commit()
+}
+
+// UpdateTargetSchedule patches the time window of a target of an unfinished
+// mission. It has to fit into the window of the mission and a changed
+// deadline has to be in the future.
+func (s *TargetStore) UpdateTargetSchedule(ctx context.Context, missionID, targetID int64, patch SchedulePatch) (*Target, error) {
+	query := `UPDATE targets SET starts_at = $3, deadline = $4 WHERE id = $1 AND mission_id = $2 RETURNING ` + targetColumns
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockMission(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if missionFinished(status) {
+		return nil, MissionCompleted
+	}
+	var current Schedule
+	err = tx.QueryRowContext(ctx, `SELECT starts_at, deadline FROM targets WHERE id = $1 AND mission_id = $2`, targetID, missionID).
+		Scan(&current.StartsAt, &current.Deadline)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	schedule := patch.apply(current)
+	if err = checkDeadlineMove(current.Deadline, schedule.Deadline); err != nil {
+		return nil, err
+	}
+	mission, err := missionSchedule(ctx, tx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSchedules(mission, schedule); err != nil {
+		return nil, err
+	}
+
+	target, err := scanTarget(tx.QueryRowContext(ctx, query, targetID, missionID, schedule.StartsAt, schedule.Deadline))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return target, tx.Commit()
+}
+
+// MarkOverdueMissions marks the unfinished missions whose deadline has passed
+// and logs an overdue event for each. It returns the ids of the missions it
+// marked, each mission is only marked once.
+func (s *MissionStore) MarkOverdueMissions(ctx context.Context) ([]int64, error) {
+	query := `
+	UPDATE missions SET overdue_at = now()
+	WHERE id IN (
+		SELECT id FROM missions
+		WHERE deadline < now() AND overdue_at IS NULL AND completed = false
+		ORDER BY deadline, id
+		LIMIT 100
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, cat_id`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	type marked struct {
+		id    int64
+		catID *int64
+	}
+	var missions []marked
+	for rows.Next() {
+		var m marked
+		if err = rows.Scan(&m.id, &m.catID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		missions = append(missions, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(missions))
+	for _, m := range missions {
+		if err = recordMissionEvent(ctx, tx, m.id, m.catID, MissionEventOverdue, "deadline passed"); err != nil {
+			return nil, err
+		}
+		ids = append(ids, m.id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
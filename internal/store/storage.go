@@ -4,40 +4,105 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
 var (
-	QueryTimeOut      = 5 * time.Second
-	ErrNotFound       = errors.New("record not found")
-	MissionedAssigned = errors.New("mission assigned")
-	TargetAmountError = errors.New("target amount error")
-	ViolatePK         = errors.New("violate pk error")
-	MissionCompleted  = errors.New("missiion completed")
+	QueryTimeOut         = 5 * time.Second
+	ErrNotFound          = errors.New("record not found")
+	MissionedAssigned    = errors.New("mission assigned")
+	TargetAmountError    = errors.New("target amount error")
+	ViolatePK            = errors.New("violate pk error")
+	MissionCompleted     = errors.New("missiion completed")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	CatOnMission         = errors.New("cat has active mission")
+	BreedRejected        = errors.New("cat breed rejected")
+	ErrInvalidTransition = errors.New("invalid mission transition")
+	ErrInvalidSchedule   = errors.New("invalid schedule")
+	SameMission          = errors.New("cat is already on this mission")
 )
 
+// ActiveMissionError reports the incomplete missions that block a change to
+// a cat. It matches CatOnMission with errors.Is.
+type ActiveMissionError struct {
+	CatID      int64   `json:"cat_id"`
+	MissionIDs []int64 `json:"mission_ids"`
+}
+
+func (e *ActiveMissionError) Error() string {
+	return fmt.Sprintf("cat %d has active missions %v", e.CatID, e.MissionIDs)
+}
+
+func (e *ActiveMissionError) Is(target error) bool {
+	return target == CatOnMission
+}
+
+// Storage groups the stores by entity. The interfaces are named so stores
+// can be wrapped, e.g. by the read-through cache.
 type Storage struct {
-	Cat interface {
-		CreateSpyCat(ctx context.Context, spyCat *Cat) error
-		DeleteSpyCat(ctx context.Context, id int64) error
-		GetByID(ctx context.Context, id int64) (*Cat, error)
-		UpdateSpyCat(ctx context.Context, spyCat *Cat) error
-		GetPaginatedSpyCatList(ctx context.Context, paginatedQuery PaginatedQuery) ([]*Cat, error)
-	}
-	Mission interface {
-		CreateMission(ctx context.Context, mission *MissionWithTargets) error
-		DeleteMission(ctx context.Context, id int64) error
-		UpdateMissionStatus(ctx context.Context, mission *UpdatedMission) error
-		AddCatToMission(ctx context.Context, catID, missionID int64) error
-		GetMissionList(ctx context.Context) ([]*MissionWithMetadata, error)
-		GetOneMission(ctx context.Context, id int64) (*MissionWithMetadata, error)
-	}
-	Target interface {
-		UpdateTargetNote(ctx context.Context, updateNote *UpdateTargetNote) error
-		UpdateTargetStatus(ctx context.Context, updateTargetStatus *UpdateTargetStatus) error
-		DeleteTarget(ctx context.Context, missionID, targetID int64) error
-		AddTarget(ctx context.Context, target *Target) error
-	}
+	Cat     CatStorage
+	Mission MissionStorage
+	Salary  SalaryStorage
+	Target  TargetStorage
+	Breed   BreedStorage
+}
+
+type CatStorage interface {
+	CreateSpyCat(ctx context.Context, spyCat *Cat) error
+	CreateSpyCats(ctx context.Context, spyCats []*Cat) error
+	DeleteSpyCat(ctx context.Context, id int64, opts DeleteCatOptions) error
+	GetByID(ctx context.Context, id int64) (*Cat, error)
+	UpdateSpyCat(ctx context.Context, id int64, patch *CatPatch) (*Cat, error)
+	GetPaginatedSpyCatList(ctx context.Context, paginatedQuery CatListQuery) (*Page[*Cat], error)
+	RestoreSpyCat(ctx context.Context, id int64) (*Cat, error)
+	PurgeSpyCat(ctx context.Context, id int64) error
+	ExportSpyCats(ctx context.Context, filter CatListQuery, fn func(*Cat) error) error
+	GetPendingBreedCats(ctx context.Context, limit int) ([]*Cat, error)
+	ResolveBreed(ctx context.Context, id int64, breed, status string) error
+}
+
+type MissionStorage interface {
+	CreateMission(ctx context.Context, mission *MissionWithTargets) error
+	DeleteMission(ctx context.Context, id int64) error
+	UpdateMissionStatus(ctx context.Context, mission *UpdatedMission) error
+	TransitionMission(ctx context.Context, missionID int64, to, reason string) (*Mission, error)
+	UpdateMissionSchedule(ctx context.Context, missionID int64, patch SchedulePatch) (*Mission, error)
+	MarkOverdueMissions(ctx context.Context) ([]int64, error)
+	AddCatToMission(ctx context.Context, catID, missionID int64) error
+	UnassignCat(ctx context.Context, missionID int64, reason string) error
+	ReassignCat(ctx context.Context, fromMissionID, toMissionID int64, reason string) error
+	GetMissionList(ctx context.Context, paginatedQuery MissionListQuery) (*Page[*MissionWithMetadata], error)
+	GetOneMission(ctx context.Context, id int64) (*MissionDetail, error)
+	GetCatMissions(ctx context.Context, catID int64) ([]*CatAssignment, error)
+	ExportMissions(ctx context.Context, filter MissionListQuery, fn func(*MissionWithMetadata) error) error
+}
+
+type SalaryStorage interface {
+	GetSalaryHistory(ctx context.Context, catID int64, at *time.Time) ([]*SalaryChange, error)
+	ScheduleSalaryChange(ctx context.Context, change *SalaryChange) error
+	ApplyDueSalaryChanges(ctx context.Context) ([]*SalaryChange, error)
+}
+
+type TargetStorage interface {
+	UpdateTargetNote(ctx context.Context, updateNote *UpdateTargetNote) error
+	UpdateTargetStatus(ctx context.Context, updateTargetStatus *UpdateTargetStatus) error
+	DeleteTarget(ctx context.Context, missionID, targetID int64) error
+	AddTarget(ctx context.Context, target *Target) error
+	UpdateTargetSchedule(ctx context.Context, missionID, targetID int64, patch SchedulePatch) (*Target, error)
+	GetTarget(ctx context.Context, missionID, targetID int64) (*Target, error)
+	GetMissionTargets(ctx context.Context, missionID int64) ([]Target, error)
+	GetTargetList(ctx context.Context, paginatedQuery TargetListQuery) (*Page[*Target], error)
+	ExportTargets(ctx context.Context, filter TargetListQuery, fn func(*Target) error) error
+}
+
+type BreedStorage interface {
+	UpsertBreeds(ctx context.Context, breeds []*Breed) (int, error)
+	CatBreedExists(ctx context.Context, name string) (bool, error)
+	MatchBreed(ctx context.Context, name string) (*Breed, error)
+	BreedNames(ctx context.Context) ([]string, error)
+	GetBreedList(ctx context.Context, paginatedQuery BreedListQuery) (*Page[*BreedWithCats], error)
+	GetBreed(ctx context.Context, name string) (*BreedWithCats, error)
 }
 
 func NewStorage(db *sql.DB) Storage {
@@ -45,5 +110,7 @@ func NewStorage(db *sql.DB) Storage {
 		Cat:     &CatStore{db},
 		Mission: &MissionStore{db},
 		Target:  &TargetStore{db},
+		Salary:  &SalaryStore{db},
+		Breed:   &BreedStore{db},
 	}
 }
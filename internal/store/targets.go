@@ -3,8 +3,11 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/lib/pq"
 	"log"
+	"strings"
 	"time"
 )
 
@@ -15,6 +18,7 @@ type Target struct {
 	Country   string `json:"country"`
 	Notes     string `json:"notes"`
 	Completed bool   `json:"completed"`
+	Schedule
 }
 
 type UpdateTargetNote struct {
@@ -61,11 +65,24 @@ func (s *TargetStore) UpdateTargetStatus(ctx context.Context, updateTargetStatus
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
-	tx, _ := s.db.BeginTx(ctx, nil)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Finished missions are frozen, their targets included.
+	status, catID, err := lockMission(ctx, tx, updateTargetStatus.MissionID)
+	if err != nil {
+		return err
+	}
+	if missionFinished(status) {
+		return MissionCompleted
+	}
+
 	var id int64
-	err := tx.QueryRowContext(ctx, updateQuery, updateTargetStatus.Status, updateTargetStatus.ID, updateTargetStatus.MissionID).Scan(&id)
+	err = tx.QueryRowContext(ctx, updateQuery, updateTargetStatus.Status, updateTargetStatus.ID, updateTargetStatus.MissionID).Scan(&id)
 	if err != nil {
-		_ = tx.Rollback()
 		switch err {
 		case sql.ErrNoRows:
 			return ErrNotFound
@@ -78,16 +95,16 @@ func (s *TargetStore) UpdateTargetStatus(ctx context.Context, updateTargetStatus
 		allTargetsCompleted := `SELECT NOT EXISTS (
     	SELECT 1 FROM targets WHERE mission_id = $1 AND completed = false)`
 		var completed bool
-		err = tx.QueryRowContext(ctx, allTargetsCompleted, updateTargetStatus.ID).Scan(&completed)
+		err = tx.QueryRowContext(ctx, allTargetsCompleted, updateTargetStatus.MissionID).Scan(&completed)
 		if err != nil {
-			_ = tx.Rollback()
 			return err
 		}
+		// The mission completes with its last target, if the lifecycle lets
+		// it. A mission that cannot complete yet, e.g. one without a cat,
+		// keeps its state.
 		if completed {
-			updateMissionStatus := `UPDATE missions SET completed = true WHERE id = $1`
-			_, err = tx.ExecContext(ctx, updateMissionStatus, updateTargetStatus.MissionID)
-			if err != nil {
-				_ = tx.Rollback()
+			err = completeMission(ctx, tx, updateTargetStatus.MissionID, status, catID, "all targets completed")
+			if err != nil && !errors.Is(err, ErrInvalidTransition) {
 				return err
 			}
 		}
@@ -122,6 +139,8 @@ func (s *TargetStore) AddTarget(ctx context.Context, target *Target) error {
 	query := `
 	SELECT
 	  m.completed,
+	  m.starts_at,
+	  m.deadline,
 	  (SELECT COUNT(*) FROM targets WHERE mission_id = $1) AS existing_count
 	FROM missions m
 	WHERE m.id = $1;
@@ -129,8 +148,9 @@ func (s *TargetStore) AddTarget(ctx context.Context, target *Target) error {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 	var completed bool
+	var mission Schedule
 	var count int64
-	err := s.db.QueryRowContext(ctx, query, target.MissionID).Scan(&completed, &count)
+	err := s.db.QueryRowContext(ctx, query, target.MissionID).Scan(&completed, &mission.StartsAt, &mission.Deadline, &count)
 	if err != nil {
 		switch err {
 		case sql.ErrNoRows:
@@ -143,8 +163,11 @@ func (s *TargetStore) AddTarget(ctx context.Context, target *Target) error {
 	if completed || count >= 3 {
 		return TargetAmountError
 	}
-	insertQuery := `INSERT INTO targets (mission_id, name, country, notes, completed) VALUES ($1, $2, $3, $4, $5)`
-	_, err = s.db.ExecContext(ctx, insertQuery, target.MissionID, target.Name, target.Country, target.Notes, completed)
+	if err = checkSchedules(mission, target.Schedule); err != nil {
+		return err
+	}
+	insertQuery := `INSERT INTO targets (mission_id, name, country, notes, completed, starts_at, deadline) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = s.db.ExecContext(ctx, insertQuery, target.MissionID, target.Name, target.Country, target.Notes, completed, target.StartsAt, target.Deadline)
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
 			if pgErr.Code == "23505" {
@@ -155,3 +178,183 @@ func (s *TargetStore) AddTarget(ctx context.Context, target *Target) error {
 	}
 	return nil
 }
+
+// targetColumns is the column list every target read selects, in the order
+// scanTarget expects.
+const targetColumns = `id, mission_id, name, country, COALESCE(notes, ''), completed, starts_at, deadline`
+
+func scanTarget(row scanner) (*Target, error) {
+	t := &Target{}
+	err := row.Scan(&t.ID, &t.MissionID, &t.Name, &t.Country, &t.Notes, &t.Completed, &t.StartsAt, &t.Deadline)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *TargetStore) GetTarget(ctx context.Context, missionID, targetID int64) (*Target, error) {
+	query := `SELECT ` + targetColumns + ` FROM targets WHERE id = $1 AND mission_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	target, err := scanTarget(s.db.QueryRowContext(ctx, query, targetID, missionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return target, nil
+}
+
+// GetMissionTargets returns every target of the mission in id order. A
+// missing mission is ErrNotFound, a mission without targets an empty list.
+func (s *TargetStore) GetMissionTargets(ctx context.Context, missionID int64) ([]Target, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM missions WHERE id = $1)`, missionID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	targets, err := missionTargets(ctx, s.db, []int64{missionID})
+	if err != nil {
+		return nil, err
+	}
+	if targets[missionID] == nil {
+		return []Target{}, nil
+	}
+	return targets[missionID], nil
+}
+
+// targetFilter builds the WHERE clause of a target list query.
+func targetFilter(q TargetListQuery) (string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Country != "" {
+		add("LOWER(country) = LOWER($%d)", q.Country)
+	}
+	if q.Completed != nil {
+		add("completed = $%d", *q.Completed)
+	}
+	if q.Search != "" {
+		add("name ILIKE '%%' || $%d || '%%'", escapeLike(q.Search))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *TargetStore) GetTargetList(ctx context.Context, paginatedQuery TargetListQuery) (*Page[*Target], error) {
+	cursor, err := paginatedQuery.cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	where, filterArgs := targetFilter(paginatedQuery)
+	condition, orderBy, args := keyset("id", "id", "asc", cursor, filterArgs)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	page := &Page[*Target]{}
+	if paginatedQuery.WithTotal {
+		var total int64
+		if err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM targets `+where, filterArgs...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	args = append(args, paginatedQuery.Limit+1)
+	query := fmt.Sprintf("SELECT %s FROM targets %s ORDER BY %s LIMIT $%d", targetColumns, andWhere(where, condition), orderBy, len(args))
+	if !paginatedQuery.CursorMode && paginatedQuery.Offset > 0 {
+		args = append(args, paginatedQuery.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []*Target{}
+	for rows.Next() {
+		target, err := scanTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paginate(page, targets, paginatedQuery.Limit, cursor, func(t *Target) Cursor {
+		return Cursor{Sort: "id", ID: t.ID}
+	})
+	return page, nil
+}
+
+// queryer is what *sql.DB and *sql.Tx have in common for reads.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// missionTargets loads the targets of all the given missions in one query,
+// keyed by mission id.
+func missionTargets(ctx context.Context, db queryer, missionIDs []int64) (map[int64][]Target, error) {
+	query := `SELECT ` + targetColumns + ` FROM targets WHERE mission_id = ANY($1) ORDER BY mission_id, id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(missionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := make(map[int64][]Target, len(missionIDs))
+	for rows.Next() {
+		t, err := scanTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets[t.MissionID] = append(targets[t.MissionID], *t)
+	}
+	return targets, rows.Err()
+}
+
+// ExportTargets streams the targets matching filter to fn in id order.
+func (s *TargetStore) ExportTargets(ctx context.Context, filter TargetListQuery, fn func(*Target) error) error {
+	where, args := targetFilter(filter)
+	rows, err := s.db.QueryContext(ctx, `SELECT `+targetColumns+` FROM targets `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		target, err := scanTarget(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(target); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		action string // allow, success, failure or wait
+		err    error
+		state  BreakerState
+	}
+	const cooldown = 20 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens after threshold failures",
+			steps: []step{
+				{action: "allow", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "allow", state: BreakerClosed},
+				{action: "failure", state: BreakerOpen},
+				{action: "allow", err: ErrCircuitOpen, state: BreakerOpen},
+			},
+		},
+		{
+			name: "success resets the failure count",
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "success", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+			},
+		},
+		{
+			name: "a successful probe closes it",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure", state: BreakerOpen},
+				{action: "wait", state: BreakerHalfOpen},
+				{action: "allow", state: BreakerHalfOpen},
+				{action: "allow", err: ErrCircuitOpen, state: BreakerHalfOpen},
+				{action: "success", state: BreakerClosed},
+				{action: "allow", state: BreakerClosed},
+			},
+		},
+		{
+			name: "a failed probe opens it again",
+			steps: []step{
+				{action: "failure"},
+				{action: "failure", state: BreakerOpen},
+				{action: "wait", state: BreakerHalfOpen},
+				{action: "allow", state: BreakerHalfOpen},
+				{action: "failure", state: BreakerOpen},
+				{action: "allow", err: ErrCircuitOpen, state: BreakerOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(2, cooldown)
+			for i, s := range tt.steps {
+				var err error
+				switch s.action {
+				case "allow":
+					err = b.allow()
+				case "success":
+					b.success()
+				case "failure":
+					b.failure()
+				case "wait":
+					time.Sleep(cooldown + 5*time.Millisecond)
+				}
+				if !errors.Is(err, s.err) {
+					t.Fatalf("step %d %s: error = %v, want %v", i, s.action, err, s.err)
+				}
+				if s.state != "" && b.State() != s.state {
+					t.Fatalf("step %d %s: state = %s, want %s", i, s.action, b.State(), s.state)
+				}
+			}
+		})
+	}
+}
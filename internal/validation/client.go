@@ -1,47 +1,238 @@
 package validation
 
 import (
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
+	"golang.org/x/sync/singleflight"
+	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 )
 
+var (
+	ErrCircuitOpen = errors.New("breed api circuit open")
+	ErrUnavailable = errors.New("breed api unavailable")
+)
+
+// Breed is a cat breed as published by TheCatAPI.
+type Breed struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Origin      string `json:"origin"`
+	Temperament string `json:"temperament"`
+	Description string `json:"description"`
+}
+
 type Client struct {
 	url        string
 	httpClient *http.Client
+	cacheTTL   time.Duration
+	maxRetries int
+	backoff    time.Duration
+	breaker    *breaker
+	// group lets concurrent callers share one fetch, mu only guards the
+	// cached list and is never held across a request.
+	group singleflight.Group
+
+	mu        sync.Mutex
+	breeds    []Breed
+	etag      string
+	fetchedAt time.Time
+}
+
+type ClientOption func(*Client)
+
+// WithCacheTTL sets how long a downloaded breed list is served without
+// asking the API again.
+func WithCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the base
+// delay of the exponential backoff between attempts.
+func WithRetries(retries int, backoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxRetries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithBreaker opens the circuit after threshold failed fetches in a row and
+// keeps it open for cooldown.
+func WithBreaker(threshold int, cooldown time.Duration) ClientOption {
+	return func(c *Client) {
+		c.breaker = newBreaker(threshold, cooldown)
+	}
 }
 
-func NewClient(url string) *Client {
-	return &Client{
+func NewClient(url string, opts ...ClientOption) *Client {
+	c := &Client{
 		url: url,
 		httpClient: &http.Client{
 			Timeout: time.Second * 10,
 		},
+		cacheTTL:   time.Hour,
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+		breaker:    newBreaker(5, 30*time.Second),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c *Client) CatBreedExists(breed string) (bool, error) {
-	resp, err := c.httpClient.Get(c.url)
-	if err != nil {
-		return false, err
+// State is the current state of the circuit breaker.
+func (c *Client) State() BreakerState {
+	return c.breaker.State()
+}
+
+// Breeds returns the breed list, from memory while it is fresher than the
+// cache TTL and otherwise revalidated against the API.
+func (c *Client) Breeds(ctx context.Context) ([]Breed, error) {
+	return c.breedList(ctx, false)
+}
+
+// Refresh is Breeds without the TTL, the list is always revalidated.
+func (c *Client) Refresh(ctx context.Context) ([]Breed, error) {
+	return c.breedList(ctx, true)
+}
+
+func (c *Client) breedList(ctx context.Context, revalidate bool) ([]Breed, error) {
+	if breeds, ok := c.cached(); ok && !revalidate {
+		return breeds, nil
 	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
+
+	// The fetch is shared, so it must not fail because the caller that
+	// started it gave up.
+	result := c.group.DoChan("breeds", func() (any, error) {
+		return c.load(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]Breed), nil
+	}
+}
+
+// cached returns the list in memory and whether it is within the cache TTL.
+func (c *Client) cached() ([]Breed, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.breeds, c.breeds != nil && time.Since(c.fetchedAt) < c.cacheTTL
+}
+
+// load fetches the list through the circuit breaker.
+func (c *Client) load(ctx context.Context) ([]Breed, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	if err := c.fetch(ctx); err != nil {
+		c.breaker.failure()
+		return nil, err
+	}
+	c.breaker.success()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.breeds, nil
+}
+
+// fetch downloads the list into the cache, retrying transient failures.
+func (c *Client) fetch(ctx context.Context) error {
+	var err error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.retryDelay(attempt)); err != nil {
+				return err
+			}
+		}
+
+		var retry bool
+		retry, err = c.fetchOnce(ctx)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// fetchOnce does a single conditional request and reports whether a failure
+// is worth retrying.
+func (c *Client) fetchOnce(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
 	if err != nil {
 		return false, err
 	}
-	var breeds []map[string]interface{}
-	if err = json.Unmarshal(bodyBytes, &breeds); err != nil {
-		return false, err
+	c.mu.Lock()
+	cached := c.breeds != nil
+	if c.etag != "" && cached {
+		req.Header.Set("If-None-Match", c.etag)
 	}
+	c.mu.Unlock()
 
-	for _, b := range breeds {
-		if name, ok := b["name"].(string); ok {
-			if name == breed {
-				return true, nil
-			}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
 		}
+		return true, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		c.mu.Lock()
+		c.fetchedAt = time.Now()
+		c.mu.Unlock()
+		return false, nil
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("%w: %s", ErrUnavailable, resp.Status)
+	default:
+		return false, fmt.Errorf("breed api returned %s", resp.Status)
+	}
+
+	var breeds []Breed
+	if err = json.NewDecoder(resp.Body).Decode(&breeds); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrUnavailable, err)
 	}
+	c.mu.Lock()
+	c.breeds = breeds
+	c.etag = resp.Header.Get("ETag")
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
 	return false, nil
 }
+
+// retryDelay doubles the base delay per attempt and adds up to as much again
+// at random, so clients that failed together do not retry together.
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.backoff << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package validation
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeBreed folds case and collapses whitespace, so "  british
+// SHORTHAIR" and "British Shorthair" compare equal.
+func NormalizeBreed(breed string) string {
+	return strings.ToLower(strings.Join(strings.Fields(breed), " "))
+}
+
+// Suggest returns up to limit of names closest to breed by edit distance,
+// nearest first. Names too far off to be a plausible typo are left out.
+func Suggest(breed string, names []string, limit int) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	input := NormalizeBreed(breed)
+	maxDistance := len([]rune(input))/2 + 1
+	var candidates []candidate
+	for _, name := range names {
+		d := editDistance(input, NormalizeBreed(name))
+		if d <= maxDistance {
+			candidates = append(candidates, candidate{name, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	suggestions := []string{}
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+	return suggestions
+}
+
+// editDistance is the Levenshtein distance between a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,63 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeBreed(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"British Shorthair", "british shorthair"},
+		{"  british \t SHORTHAIR ", "british shorthair"},
+		{"Abyssinian", "abyssinian"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeBreed(tt.in); got != tt.want {
+			t.Errorf("NormalizeBreed(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"siamese", "siamese", 0},
+		{"siamse", "siamese", 1},
+		{"kitten", "sitting", 3},
+		{"bengál", "bengal", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"Bengal", "Bombay", "Siamese", "Somali", "Sphynx"}
+	tests := []struct {
+		breed string
+		limit int
+		want  []string
+	}{
+		{"siamse", 3, []string{"Siamese"}},
+		{"  BENGAL ", 1, []string{"Bengal"}},
+		{"Somal", 1, []string{"Somali"}},
+		{"Bomaly", 2, []string{"Bombay", "Somali"}},
+		{"Maine Coon", 3, []string{}},
+		{"siamse", 0, []string{}},
+	}
+	for _, tt := range tests {
+		if got := Suggest(tt.breed, names, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q, %d) = %v, want %v", tt.breed, tt.limit, got, tt.want)
+		}
+	}
+}
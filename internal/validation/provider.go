@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreedProvider is a source of the breed catalog.
+type BreedProvider interface {
+	Breeds(ctx context.Context) ([]Breed, error)
+}
+
+// refresher is implemented by providers that cache and can be told to skip
+// the cache.
+type refresher interface {
+	Refresh(ctx context.Context) ([]Breed, error)
+}
+
+// Refresh asks p for an up-to-date list, bypassing any cache it keeps.
+func Refresh(ctx context.Context, p BreedProvider) ([]Breed, error) {
+	if r, ok := p.(refresher); ok {
+		return r.Refresh(ctx)
+	}
+	return p.Breeds(ctx)
+}
+
+// FileProvider reads breeds from a JSON or YAML file, picked by extension,
+// in the same shape TheCatAPI returns. The file is read on every call so it
+// can be edited without a restart.
+type FileProvider struct {
+	Path string
+}
+
+func (p *FileProvider) Breeds(_ context.Context) ([]Breed, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(p.Path)) {
+	case ".yaml", ".yml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", p.Path, err)
+		}
+	case ".json":
+	default:
+		return nil, fmt.Errorf("unsupported breed file %s", p.Path)
+	}
+
+	var breeds []Breed
+	if err = json.Unmarshal(data, &breeds); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", p.Path, err)
+	}
+	return withIDs(breeds), nil
+}
+
+// StaticProvider serves a fixed list.
+type StaticProvider []Breed
+
+// NewStaticProvider builds a static list from bare breed names.
+func NewStaticProvider(names ...string) StaticProvider {
+	breeds := make(StaticProvider, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			breeds = append(breeds, Breed{Name: name})
+		}
+	}
+	return breeds
+}
+
+func (p StaticProvider) Breeds(_ context.Context) ([]Breed, error) {
+	return withIDs(p), nil
+}
+
+// ChainProvider returns the list of the first provider that succeeds.
+type ChainProvider []BreedProvider
+
+func (p ChainProvider) Breeds(ctx context.Context) ([]Breed, error) {
+	return p.first(ctx, func(provider BreedProvider) ([]Breed, error) {
+		return provider.Breeds(ctx)
+	})
+}
+
+func (p ChainProvider) Refresh(ctx context.Context) ([]Breed, error) {
+	return p.first(ctx, func(provider BreedProvider) ([]Breed, error) {
+		return Refresh(ctx, provider)
+	})
+}
+
+func (p ChainProvider) first(ctx context.Context, fetch func(BreedProvider) ([]Breed, error)) ([]Breed, error) {
+	var errs []error
+	for _, provider := range p {
+		breeds, err := fetch(provider)
+		if err == nil {
+			return breeds, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no breed providers configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// withIDs fills in missing ids with the breed name, hand-written lists
+// rarely carry TheCatAPI ids.
+func withIDs(breeds []Breed) []Breed {
+	out := make([]Breed, len(breeds))
+	for i, b := range breeds {
+		if b.ID == "" {
+			b.ID = b.Name
+		}
+		out[i] = b
+	}
+	return out
+}
@@ -1,13 +1,19 @@
 package validation
 
 import (
+	"context"
 	"github.com/go-playground/validator/v10"
 )
 
-func RegisterCatValidator(v *validator.Validate, client *Client) {
-	v.RegisterValidation("breed-exits", func(fl validator.FieldLevel) bool {
+// BreedChecker tells whether a breed is known.
+type BreedChecker interface {
+	CatBreedExists(ctx context.Context, breed string) (bool, error)
+}
+
+func RegisterCatValidator(v *validator.Validate, checker BreedChecker) {
+	v.RegisterValidationCtx("breed-exits", func(ctx context.Context, fl validator.FieldLevel) bool {
 		breed := fl.Field().String()
-		ok, err := client.CatBreedExists(breed)
+		ok, err := checker.CatBreedExists(ctx, breed)
 		return err == nil && ok
 	})
 }